@@ -0,0 +1,576 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// runFunc runs a command with its positional arguments and returns the data to
+// write, if any.
+type runFunc func(ctx context.Context, args []string) (interface{}, error)
+
+// command is a subcommand of a group, such as "projects list".
+type command struct {
+	group string
+	name  string
+	// args describes the positional arguments, of which there must be nargs.
+	args  string
+	nargs int
+	help  string
+	// setup registers the flags of the command and returns its runFunc.
+	setup func(a *app, fs *flag.FlagSet) runFunc
+}
+
+func (c *command) String() string {
+	return c.group + " " + c.name
+}
+
+var commands = []*command{
+	{
+		group: "projects", name: "list", help: "list projects",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			q := queryFlags(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				options, err := q.options()
+				if err != nil {
+					return nil, err
+				}
+				if q.all {
+					return a.client.ListAllProjectsWithContext(ctx, options, q.iterator())
+				}
+				res, err := a.client.GetAllProjectsWithContext(ctx, options)
+				if err != nil {
+					return nil, err
+				}
+				return res.Projects, nil
+			}
+		},
+	},
+	{
+		group: "projects", name: "get", args: "<extProjectId>", nargs: 1, help: "get a project",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				res, err := a.client.GetProjectByWithContext(ctx, args[0])
+				if err != nil {
+					return nil, err
+				}
+				return res.Project, nil
+			}
+		},
+	},
+	{
+		group: "projects", name: "create", help: "create a project from a CreateProjectCriteria JSON file",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := fileFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				criteria := &samplify.CreateProjectCriteria{}
+				if err := a.readJSON(*file, criteria); err != nil {
+					return nil, err
+				}
+				res, err := a.client.CreateProjectWithContext(ctx, criteria)
+				if err != nil {
+					return nil, err
+				}
+				return res.Project, nil
+			}
+		},
+	},
+	{
+		group: "projects", name: "update", help: "update a project from an UpdateProjectCriteria JSON file",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := fileFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				criteria := &samplify.UpdateProjectCriteria{}
+				if err := a.readJSON(*file, criteria); err != nil {
+					return nil, err
+				}
+				res, err := a.client.UpdateProjectWithContext(ctx, criteria)
+				if err != nil {
+					return nil, err
+				}
+				return res.Project, nil
+			}
+		},
+	},
+	{
+		group: "projects", name: "buy", args: "<extProjectId>", nargs: 1,
+		help: "buy the line items of a project from a JSON list of BuyProjectCriteria",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := fileFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				var buy []*samplify.BuyProjectCriteria
+				if err := a.readJSON(*file, &buy); err != nil {
+					return nil, err
+				}
+				res, err := a.client.BuyProjectWithContext(ctx, args[0], buy)
+				if err != nil {
+					return nil, err
+				}
+				return res.List, nil
+			}
+		},
+	},
+	{
+		group: "projects", name: "close", args: "<extProjectId>", nargs: 1, help: "close a project",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				res, err := a.client.CloseProjectWithContext(ctx, args[0])
+				if err != nil {
+					return nil, err
+				}
+				return res.Project, nil
+			}
+		},
+	},
+	{
+		group: "projects", name: "report", args: "<extProjectId>", nargs: 1, help: "get the report of a project",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				res, err := a.client.GetProjectReportWithContext(ctx, args[0])
+				if err != nil {
+					return nil, err
+				}
+				return res.Report, nil
+			}
+		},
+	},
+	{
+		group: "projects", name: "plan", help: "show the changes that would bring a project to a YAML or JSON spec file",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := specFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				_, err := a.plan(ctx, *file)
+				return nil, err
+			}
+		},
+	},
+	{
+		group: "projects", name: "apply", help: "create and update a project and its line items from a YAML or JSON spec file",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := specFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				plan, err := a.plan(ctx, *file)
+				if err != nil {
+					return nil, err
+				}
+				return nil, a.client.ApplyPlanWithContext(ctx, plan)
+			}
+		},
+	},
+	{
+		group: "lineitems", name: "list", args: "<extProjectId>", nargs: 1, help: "list the line items of a project",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			q := queryFlags(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				options, err := q.options()
+				if err != nil {
+					return nil, err
+				}
+				if q.all {
+					return a.client.ListAllLineItemsWithContext(ctx, args[0], options, q.iterator())
+				}
+				res, err := a.client.GetAllLineItemsWithContext(ctx, args[0], options)
+				if err != nil {
+					return nil, err
+				}
+				return res.List, nil
+			}
+		},
+	},
+	{
+		group: "lineitems", name: "get", args: "<extProjectId> <extLineItemId>", nargs: 2, help: "get a line item",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				res, err := a.client.GetLineItemByWithContext(ctx, args[0], args[1])
+				if err != nil {
+					return nil, err
+				}
+				return res.Item, nil
+			}
+		},
+	},
+	{
+		group: "lineitems", name: "add", args: "<extProjectId>", nargs: 1,
+		help: "add a line item to a project from a CreateLineItemCriteria JSON file",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := fileFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				criteria := &samplify.CreateLineItemCriteria{}
+				if err := a.readJSON(*file, criteria); err != nil {
+					return nil, err
+				}
+				res, err := a.client.AddLineItemWithContext(ctx, args[0], criteria)
+				if err != nil {
+					return nil, err
+				}
+				return res.Item, nil
+			}
+		},
+	},
+	stateCommand(samplify.ActionLaunched),
+	stateCommand(samplify.ActionPaused),
+	stateCommand(samplify.ActionClosed),
+	{
+		group: "lineitems", name: "quotacell", args: "<extProjectId> <extLineItemId> <quotaCellId> launch|pause", nargs: 4,
+		help: "set the status of a quota cell",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				res, err := a.client.SetQuotaCellStatusWithContext(ctx, args[0], args[1], args[2], samplify.Action(args[3]))
+				if err != nil {
+					return nil, err
+				}
+				return &res.QuotaCell, nil
+			}
+		},
+	},
+	{
+		group: "feasibility", name: "get", args: "<extProjectId>", nargs: 1, help: "get the feasibility of the line items of a project",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			wait := fs.Bool("wait", false, "wait until the feasibility of every line item is known")
+			interval := fs.Duration("interval", 30*time.Second, "interval between polls with -wait")
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				if *wait {
+					res, err := a.client.WaitForFeasibility(ctx, args[0], &samplify.WaitForFeasibilityOptions{Interval: *interval})
+					if res == nil {
+						return nil, err
+					}
+					// Write the feasibility of the line items along with the
+					// FeasibilityError of those that failed.
+					return res.List, err
+				}
+				res, err := a.client.GetFeasibilityWithContext(ctx, args[0], nil)
+				if err != nil {
+					return nil, err
+				}
+				return res.List, nil
+			}
+		},
+	},
+	{
+		group: "attributes", name: "list", args: "<countryISOCode> <languageISOCode>", nargs: 2,
+		help: "list the attributes of a country and language",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			q := queryFlags(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				options, err := q.options()
+				if err != nil {
+					return nil, err
+				}
+				if q.all {
+					return a.client.ListAllAttributesWithContext(ctx, args[0], args[1], options, q.iterator())
+				}
+				res, err := a.client.GetAttributesWithContext(ctx, args[0], args[1], options)
+				if err != nil {
+					return nil, err
+				}
+				return res.List, nil
+			}
+		},
+	},
+	{
+		group: "countries", name: "list", help: "list the supported countries",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			q := queryFlags(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				options, err := q.options()
+				if err != nil {
+					return nil, err
+				}
+				if q.all {
+					return a.client.ListAllCountriesWithContext(ctx, options, q.iterator())
+				}
+				res, err := a.client.GetCountriesWithContext(ctx, options)
+				if err != nil {
+					return nil, err
+				}
+				return res.List, nil
+			}
+		},
+	},
+	{
+		group: "events", name: "list", help: "list events, most recent first",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			q := queryFlags(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				options, err := q.options()
+				if err != nil {
+					return nil, err
+				}
+				if q.all {
+					return a.client.ListAllEventsWithContext(ctx, options, q.iterator())
+				}
+				res, err := a.client.GetEventsWithContext(ctx, options)
+				if err != nil {
+					return nil, err
+				}
+				return res.List, nil
+			}
+		},
+	},
+	eventCommand("accept", (*samplify.Client).AcceptEventWithContext),
+	eventCommand("reject", (*samplify.Client).RejectEventWithContext),
+	{
+		group: "templates", name: "list", args: "<countryISOCode> <languageISOCode>", nargs: 2,
+		help: "list the quota plan templates of a country and language",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			q := queryFlags(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				options, err := q.options()
+				if err != nil {
+					return nil, err
+				}
+				if q.all {
+					return a.client.ListAllTemplatesWithContext(ctx, args[0], args[1], options, q.iterator())
+				}
+				res, err := a.client.GetTemplateListWithContext(ctx, args[0], args[1], options)
+				if err != nil {
+					return nil, err
+				}
+				return res.Data, nil
+			}
+		},
+	},
+	{
+		group: "templates", name: "create", help: "create a quota plan template from a TemplateCriteria JSON file",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := fileFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				criteria := &samplify.TemplateCriteria{}
+				if err := a.readJSON(*file, criteria); err != nil {
+					return nil, err
+				}
+				res, err := a.client.CreateTemplateWithContext(ctx, criteria)
+				if err != nil {
+					return nil, err
+				}
+				return res.Data, nil
+			}
+		},
+	},
+	{
+		group: "templates", name: "update", args: "<id>", nargs: 1,
+		help: "update a quota plan template from a TemplateCriteria JSON file",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := fileFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				id, err := templateID(args[0])
+				if err != nil {
+					return nil, err
+				}
+				criteria := &samplify.TemplateCriteria{}
+				if err := a.readJSON(*file, criteria); err != nil {
+					return nil, err
+				}
+				res, err := a.client.UpdateTemplateWithContext(ctx, id, criteria)
+				if err != nil {
+					return nil, err
+				}
+				return res.Data, nil
+			}
+		},
+	},
+	{
+		group: "templates", name: "delete", args: "<id>", nargs: 1, help: "delete a quota plan template",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				id, err := templateID(args[0])
+				if err != nil {
+					return nil, err
+				}
+				_, err = a.client.DeleteTemplateWithContext(ctx, id)
+				return nil, err
+			}
+		},
+	},
+	{
+		group: "permissions", name: "get", args: "<extProjectId>", nargs: 1, help: "get the permissions on a project",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				res, err := a.client.ProjectPermissionsWithContext(ctx, args[0])
+				if err != nil {
+					return nil, err
+				}
+				return res.ProjectPermissions, nil
+			}
+		},
+	},
+	{
+		group: "permissions", name: "upsert", help: "update the permissions on a project from an UpsertPermissionsCriteria JSON file",
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			file := fileFlag(fs)
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				criteria := &samplify.UpsertPermissionsCriteria{}
+				if err := a.readJSON(*file, criteria); err != nil {
+					return nil, err
+				}
+				res, err := a.client.UpsertProjectPermissionsWithContext(ctx, criteria)
+				if err != nil {
+					return nil, err
+				}
+				return res.ProjectPermissions, nil
+			}
+		},
+	},
+}
+
+// stateCommand returns the command that applies action to a line item.
+func stateCommand(action samplify.Action) *command {
+	return &command{
+		group: "lineitems", name: string(action), args: "<extProjectId> <extLineItemId>", nargs: 2,
+		help: fmt.Sprintf("%s a line item", action),
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				res, err := a.client.UpdateLineItemStateWithContext(ctx, args[0], args[1], action)
+				if err != nil {
+					return nil, err
+				}
+				return res.LineItem, nil
+			}
+		},
+	}
+}
+
+// eventCommand returns the command that accepts or rejects an event.
+func eventCommand(name string, f func(*samplify.Client, context.Context, *samplify.Event) error) *command {
+	return &command{
+		group: "events", name: name, args: "<eventId>", nargs: 1,
+		help: fmt.Sprintf("%s a reprice event", name),
+		setup: func(a *app, fs *flag.FlagSet) runFunc {
+			return func(ctx context.Context, args []string) (interface{}, error) {
+				res, err := a.client.GetEventByWithContext(ctx, args[0])
+				if err != nil {
+					return nil, err
+				}
+				if res.Event == nil {
+					return nil, fmt.Errorf("event %s not found", args[0])
+				}
+				return nil, f(a.client, ctx, res.Event)
+			}
+		},
+	}
+}
+
+// listFlags are the flags of list commands.
+type listFlags struct {
+	filters stringsFlag
+	sorts   stringsFlag
+	limit   uint
+	offset  uint
+	all     bool
+}
+
+func queryFlags(fs *flag.FlagSet) *listFlags {
+	q := &listFlags{}
+	fs.Var(&q.filters, "filter", "filter as field=value, may be repeated")
+	fs.Var(&q.sorts, "sort", "sort as field:asc or field:desc, may be repeated")
+	fs.UintVar(&q.limit, "limit", 0, "maximum number of items, across all pages with -all")
+	fs.UintVar(&q.offset, "offset", 0, "number of items to skip")
+	fs.BoolVar(&q.all, "all", false, "list all pages")
+	return q
+}
+
+// options returns the QueryOptions of the flags.
+func (q *listFlags) options() (*samplify.QueryOptions, error) {
+	values := url.Values{}
+	for _, f := range q.filters {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid filter %q, expected field=value", f)
+		}
+		values.Add(kv[0], kv[1])
+	}
+	if len(q.sorts) > 0 {
+		values.Set("sort", strings.Join(q.sorts, ","))
+	}
+	options, err := samplify.ParseQuery(values.Encode())
+	if err != nil {
+		return nil, err
+	}
+	options.Offset = q.offset
+	if !q.all {
+		options.Limit = q.limit
+	}
+	return options, nil
+}
+
+// iterator returns the IteratorOptions of the flags, for -all.
+func (q *listFlags) iterator() *samplify.IteratorOptions {
+	return &samplify.IteratorOptions{MaxItems: int(q.limit)}
+}
+
+// stringsFlag is a flag that may be repeated.
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+func fileFlag(fs *flag.FlagSet) *string {
+	return fs.String("f", "-", "JSON file with the request, - for stdin")
+}
+
+func specFlag(fs *flag.FlagSet) *string {
+	return fs.String("f", "", "YAML or JSON project spec file")
+}
+
+// plan loads the project spec in file, and writes the plan that brings the
+// project to it.
+func (a *app) plan(ctx context.Context, file string) (*samplify.Plan, error) {
+	if len(file) == 0 {
+		return nil, fmt.Errorf("no spec file, set -f")
+	}
+	spec, err := samplify.LoadSpec(file)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := a.client.PlanProjectWithContext(ctx, spec)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(a.stdout, plan)
+	return plan, nil
+}
+
+// readJSON decodes the JSON in file, or stdin if file is "-", into v.
+func (a *app) readJSON(file string, v interface{}) error {
+	var r io.Reader = a.stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+func templateID(s string) (int, error) {
+	var id int
+	if _, err := fmt.Sscan(s, &id); err != nil {
+		return 0, fmt.Errorf("invalid template id %q", s)
+	}
+	return id, nil
+}
@@ -0,0 +1,175 @@
+// Command samplify is a command-line client for the Samplify Demand API.
+//
+// Usage:
+//
+//	samplify [global flags] <group> <command> [flags] [arguments]
+//
+// Settings are read from the profile selected with -profile or
+// SAMPLIFY_PROFILE in the config file, see samplify.LoadProfile, then from the
+// SAMPLIFY_* environment variables, such as SAMPLIFY_CLIENT_ID,
+// SAMPLIFY_USERNAME and SAMPLIFY_PASSWORD, and finally from the global flags.
+// The password has no flag, so that it does not show in the process list or
+// the shell history.
+// Run "samplify help" for the list of commands.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// app holds the state shared by the commands of one invocation.
+type app struct {
+	client *samplify.Client
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+	code := run(ctx, os.Args[1:], &app{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr})
+	cancel()
+	os.Exit(code)
+}
+
+// run runs the command line args and returns the exit code. If a.client is
+// set, it is used instead of a client built from the global flags.
+func run(ctx context.Context, args []string, a *app) int {
+	fs := flag.NewFlagSet("samplify", flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	profile := fs.String("profile", "", "name of the profile in the config file")
+	var flags samplify.Profile
+	fs.StringVar(&flags.Env, "env", "", "environment: local, dev, uat or prod (default uat)")
+	fs.IntVar(&flags.Timeout, "timeout", 0, "request timeout in seconds (default 20)")
+	fs.IntVar(&flags.Retries, "retries", 0, "number of times failed requests are retried")
+	fs.StringVar(&flags.ClientID, "client-id", "", "client ID")
+	fs.StringVar(&flags.Username, "username", "", "username")
+	var out output
+	fs.StringVar(&out.format, "o", formatJSON, "output format: json, table or csv")
+	fields := fs.String("fields", "", "comma separated fields shown in table and csv output")
+	verbose := fs.Bool("v", false, "log requests to stderr")
+	debug := fs.Bool("debug", false, "log requests with their headers and bodies to stderr")
+	fs.Usage = func() { usage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if len(*fields) > 0 {
+		out.fields = strings.Split(*fields, ",")
+	}
+	if err := out.validate(); err != nil {
+		fmt.Fprintln(a.stderr, err)
+		return 2
+	}
+
+	args = fs.Args()
+	if len(args) == 0 || args[0] == "help" {
+		usage(fs)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	cmd, args := findCommand(args)
+	if cmd == nil {
+		fmt.Fprintf(a.stderr, "unknown command %q\n", strings.Join(args, " "))
+		usage(fs)
+		return 2
+	}
+
+	cfs := flag.NewFlagSet(cmd.String(), flag.ContinueOnError)
+	cfs.SetOutput(a.stderr)
+	runCmd := cmd.setup(a, cfs)
+	cfs.Usage = func() {
+		fmt.Fprintf(a.stderr, "usage: samplify %s %s\n\n%s\n", cmd, cmd.args, cmd.help)
+		cfs.PrintDefaults()
+	}
+	if err := cfs.Parse(args); err != nil {
+		return 2
+	}
+	if cmd.nargs != len(cfs.Args()) {
+		cfs.Usage()
+		return 2
+	}
+
+	if a.client == nil {
+		p, err := samplify.LoadProfile(*profile)
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return 2
+		}
+		p.Merge(&flags)
+		client, err := p.NewClient()
+		if err != nil {
+			fmt.Fprintln(a.stderr, err)
+			return 2
+		}
+		if *debug {
+			client.Options.Logger = samplify.NewTextLogger(a.stderr, samplify.LevelDebug)
+			client.Options.LogBodies = true
+		} else if *verbose {
+			client.Options.Logger = samplify.NewTextLogger(a.stderr, samplify.LevelInfo)
+		}
+		defer client.Close()
+		a.client = client
+	}
+
+	res, err := runCmd(ctx, cfs.Args())
+	if res != nil {
+		if werr := out.write(a.stdout, res); err == nil {
+			err = werr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(a.stderr, "samplify %s: %v\n", cmd, err)
+		return 1
+	}
+	return 0
+}
+
+func findCommand(args []string) (*command, []string) {
+	if len(args) < 2 {
+		return nil, args
+	}
+	for _, c := range commands {
+		if c.group == args[0] && c.name == args[1] {
+			return c, args[2:]
+		}
+	}
+	return nil, args
+}
+
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+	fmt.Fprintf(w, "usage: samplify [global flags] <group> <command> [flags] [arguments]\n\nGlobal flags:\n")
+	fs.PrintDefaults()
+	fmt.Fprintf(w, "\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	help := map[string]string{}
+	for _, c := range commands {
+		name := fmt.Sprintf("%s %s", c, c.args)
+		names = append(names, name)
+		help[name] = c.help
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(tw, "  %s\t%s\n", name, help[name])
+	}
+	tw.Flush()
+}
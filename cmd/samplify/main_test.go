@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/researchnow/go-samplifyapi-client/lib/samplifytest"
+)
+
+const project = `{
+	"extProjectId": "p1",
+	"title": "Test Survey",
+	"notificationEmails": ["api-test@researchnow.com"],
+	"category": {"surveyTopic": ["AUTOMOTIVE"]},
+	"lineItems": [{
+		"extLineItemId": "l1",
+		"title": "US College",
+		"countryISOCode": "US",
+		"languageISOCode": "en",
+		"indicativeIncidence": 20,
+		"lengthOfInterview": 10,
+		"requiredCompletes": 200
+	}]
+}`
+
+func TestRun(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+
+	tests := []struct {
+		args  []string
+		stdin string
+		code  int
+		out   string
+	}{
+		{
+			args:  []string{"projects", "create"},
+			stdin: project,
+			out:   `"extProjectId": "p1"`,
+		},
+		{
+			args: []string{"-o", "table", "-fields", "extProjectId,title,state", "projects", "list"},
+			out:  "EXTPROJECTID  TITLE        STATE\np1            Test Survey  PROVISIONED\n",
+		},
+		{
+			args: []string{"-o", "csv", "-fields", "extLineItemId,state", "lineitems", "list", "-filter", "state=PROVISIONED", "p1"},
+			out:  "extLineItemId,state\nl1,PROVISIONED\n",
+		},
+		{
+			args: []string{"-o", "csv", "-fields", "extLineItemId,state", "lineitems", "close", "p1", "l1"},
+			out:  "extLineItemId,state\nl1,CLOSED\n",
+		},
+		{
+			args: []string{"lineitems", "launch", "p1", "l1"},
+			code: 1,
+		},
+		{
+			args: []string{"-o", "table", "-fields", "nope", "projects", "get", "p1"},
+			code: 1,
+		},
+		{
+			args: []string{"projects", "get"},
+			code: 2,
+		},
+		{
+			args: []string{"-password", "secret", "projects", "list"},
+			code: 2,
+		},
+		{
+			args: []string{"projects", "rename", "p1"},
+			code: 2,
+		},
+	}
+	for _, tt := range tests {
+		var stdout, stderr bytes.Buffer
+		a := &app{client: client, stdin: strings.NewReader(tt.stdin), stdout: &stdout, stderr: &stderr}
+		code := run(context.Background(), tt.args, a)
+		if code != tt.code {
+			t.Errorf("%v: expected exit code %d, got %d: %s", tt.args, tt.code, code, stderr.String())
+		}
+		if !strings.Contains(stdout.String(), tt.out) {
+			t.Errorf("%v: expected output %q, got %q", tt.args, tt.out, stdout.String())
+		}
+	}
+}
+
+func TestListAll(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+	var stdout, stderr bytes.Buffer
+	for _, id := range []string{"p1", "p2", "p3"} {
+		a := &app{client: client, stdin: strings.NewReader(strings.Replace(project, `"p1"`, `"`+id+`"`, 1)), stdout: &stdout, stderr: &stderr}
+		if code := run(context.Background(), []string{"projects", "create"}, a); code != 0 {
+			t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+		}
+	}
+
+	stdout.Reset()
+	a := &app{client: client, stdout: &stdout, stderr: &stderr}
+	if code := run(context.Background(), []string{"-o", "csv", "-fields", "extProjectId", "projects", "list", "-all", "-limit", "2"}, a); code != 0 {
+		t.Fatalf("unexpected exit code %d: %s", code, stderr.String())
+	}
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 3 {
+		t.Errorf("expected 2 projects, got %q", stdout.String())
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+	spec := filepath.Join(t.TempDir(), "p1.json")
+	if err := ioutil.WriteFile(spec, []byte(strings.Replace(project, `"requiredCompletes"`, `"daysInField": 10, "requiredCompletes"`, 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		cmd string
+		out string
+	}{
+		{"plan", "+ create project p1\n    + line item l1\n"},
+		{"apply", "+ create project p1\n    + line item l1\n"},
+		{"plan", "no changes\n"},
+	} {
+		var stdout, stderr bytes.Buffer
+		a := &app{client: client, stdout: &stdout, stderr: &stderr}
+		if code := run(context.Background(), []string{"projects", tt.cmd, "-f", spec}, a); code != 0 {
+			t.Fatalf("%s: expected exit code 0, got %d: %s", tt.cmd, code, stderr.String())
+		}
+		if stdout.String() != tt.out {
+			t.Errorf("%s: expected output %q, got %q", tt.cmd, tt.out, stdout.String())
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// Output formats
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+	formatCSV   = "csv"
+)
+
+// timeLayout is the layout of times in table and CSV output, as used by the
+// API.
+const timeLayout = "2006/01/02 15:04:05"
+
+// output writes the data returned by commands.
+type output struct {
+	format string
+	// fields are the columns of table and CSV output. By default, all the
+	// fields with scalar values are shown.
+	fields []string
+}
+
+func (o *output) validate() error {
+	switch o.format {
+	case formatJSON, formatTable, formatCSV:
+		return nil
+	}
+	return fmt.Errorf("unknown output format %q, expected json, table or csv", o.format)
+}
+
+// write writes v, a struct or a slice of structs, in the output format.
+func (o *output) write(w io.Writer, v interface{}) error {
+	if o.format == formatJSON {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+
+	header, rows, err := o.table(v)
+	if err != nil {
+		return err
+	}
+	if o.format == formatCSV {
+		cw := csv.NewWriter(w)
+		cw.Write(header)
+		cw.WriteAll(rows)
+		return cw.Error()
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(header, "\t")))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// table returns the header and rows of data.
+func (o *output) table(data interface{}) ([]string, [][]string, error) {
+	t := reflect.TypeOf(data)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	v := indirect(reflect.ValueOf(data))
+	var items []reflect.Value
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		for i := 0; v.IsValid() && i < v.Len(); i++ {
+			if item := indirect(v.Index(i)); item.IsValid() {
+				items = append(items, item)
+			}
+		}
+	} else if v.IsValid() {
+		items = append(items, v)
+	}
+	if t.Kind() != reflect.Struct {
+		rows := make([][]string, len(items))
+		for i, item := range items {
+			rows[i] = []string{cell(item)}
+		}
+		return []string{"value"}, rows, nil
+	}
+
+	cols, err := o.columns(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.name
+	}
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = make([]string, len(cols))
+		for j, c := range cols {
+			rows[i][j] = cell(item.FieldByIndex(c.index))
+		}
+	}
+	return header, rows, nil
+}
+
+// column is a field of a struct shown in a table.
+type column struct {
+	name  string
+	index []int
+}
+
+// columns returns the selected columns of t, or its scalar fields if none are
+// selected.
+func (o *output) columns(t reflect.Type) ([]column, error) {
+	all := structColumns(t, nil)
+	if len(o.fields) == 0 {
+		var cols []column
+		for _, c := range all {
+			if isScalar(t.FieldByIndex(c.index).Type) {
+				cols = append(cols, c)
+			}
+		}
+		return cols, nil
+	}
+	cols := make([]column, 0, len(o.fields))
+	for _, name := range o.fields {
+		found := false
+		for _, c := range all {
+			if c.name == name {
+				cols = append(cols, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			names := make([]string, len(all))
+			for i, c := range all {
+				names[i] = c.name
+			}
+			return nil, fmt.Errorf("unknown field %q, available fields: %s", name, strings.Join(names, ", "))
+		}
+	}
+	return cols, nil
+}
+
+// structColumns returns the fields of t as they are named in JSON, with the
+// fields of embedded structs in place of the struct.
+func structColumns(t reflect.Type, index []int) []column {
+	var cols []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		idx := append(append([]int{}, index...), i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct && !isTime(f.Type) {
+			cols = append(cols, structColumns(f.Type, idx)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		cols = append(cols, column{name: name, index: idx})
+	}
+	return cols
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	customTimeType = reflect.TypeOf(samplify.CustomTime{})
+)
+
+func isTime(t reflect.Type) bool {
+	return t == timeType || t == customTimeType
+}
+
+// isScalar reports whether values of t are shown in tables by default.
+func isScalar(t reflect.Type) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if isTime(t) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// cell formats v for a table. Values that are not scalar are formatted as
+// JSON.
+func cell(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	if isTime(v.Type()) {
+		t := v.Interface()
+		if ct, ok := t.(samplify.CustomTime); ok {
+			t = ct.Time
+		}
+		if t.(time.Time).IsZero() {
+			return ""
+		}
+		return t.(time.Time).Format(timeLayout)
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(b)
+}
+
+// indirect follows pointers and interfaces, returning the zero Value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
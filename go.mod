@@ -1,14 +1,14 @@
 module github.com/researchnow/go-samplifyapi-client
 
-go 1.13
+go 1.18
 
 require (
 	github.com/asaskevich/govalidator v0.0.0-20190424111038-f61b66f89f4a
 	github.com/corpix/uarand v0.1.1 // indirect
 	github.com/etgryphon/stringUp v0.0.0-20121020160746-31534ccd8cac // indirect
-	github.com/hashicorp/go-retryablehttp v0.7.0
 	github.com/icrowley/fake v0.0.0-20180203215853-4178557ae428 // indirect
 	github.com/leebenson/conform v0.0.0-20190822094432-4c55492f71d7
 	github.com/researchnow/tareekh v0.0.0-20200818130035-8cc1b656a124
 	github.com/stretchr/testify v1.4.0 // indirect
+	gopkg.in/yaml.v2 v2.4.0
 )
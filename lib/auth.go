@@ -0,0 +1,442 @@
+package samplify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// renewMode selects how a tokenManager acquires new tokens.
+type renewMode int
+
+// renewMode values
+const (
+	// renewAuto uses the refresh token and falls back to a password login.
+	renewAuto renewMode = iota
+	// renewRefresh uses the refresh token only.
+	renewRefresh
+	// renewLogin always does a password login.
+	renewLogin
+)
+
+// String returns the name of m as logged, e.g. "refresh".
+func (m renewMode) String() string {
+	switch m {
+	case renewRefresh:
+		return "refresh"
+	case renewLogin:
+		return "login"
+	}
+	return "auto"
+}
+
+// tokenManager owns the tokens of a Client. It is safe for concurrent use:
+// tokens are swapped atomically and goroutines that need new tokens at the
+// same time share a single in-flight login or refresh. Acquired tokens are
+// stored in source.
+type tokenManager struct {
+	client  *Client
+	source  TokenSource
+	current atomic.Value // *TokenResponse
+
+	mu       sync.Mutex
+	inflight *tokenCall
+	updated  chan struct{} // closed when the current tokens change
+}
+
+// tokenCall is a login or refresh in progress.
+type tokenCall struct {
+	done  chan struct{}
+	token *TokenResponse
+	err   error
+}
+
+// newTokenManager returns a tokenManager that starts with the tokens stored in
+// source. If there are none, or they cannot be read, the first request logs in.
+func newTokenManager(c *Client, source TokenSource) *tokenManager {
+	m := &tokenManager{client: c, source: source, updated: make(chan struct{})}
+	t, err := source.Token()
+	if err != nil || t == nil {
+		t = &TokenResponse{}
+	}
+	m.current.Store(t)
+	return m
+}
+
+// token returns the current tokens. The returned value is shared and must not
+// be modified.
+func (m *tokenManager) token() *TokenResponse {
+	return m.current.Load().(*TokenResponse)
+}
+
+// set replaces the current tokens, and returns the error of storing them, if
+// any.
+func (m *tokenManager) set(t *TokenResponse) error {
+	m.mu.Lock()
+	m.swap(t)
+	m.mu.Unlock()
+	return m.store(t)
+}
+
+// swap replaces the current tokens and wakes up everyone waiting for them to
+// change. m.mu must be held.
+func (m *tokenManager) swap(t *TokenResponse) {
+	m.current.Store(t)
+	close(m.updated)
+	m.updated = make(chan struct{})
+}
+
+// changed returns a channel that is closed when the current tokens change.
+func (m *tokenManager) changed() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.updated
+}
+
+// store saves t in the token source. Failing to do so does not fail the
+// renewal that acquired t, as the tokens are still used from memory: the
+// error is logged, passed to BackgroundRefreshOptions.OnError and returned.
+func (m *tokenManager) store(t *TokenResponse) error {
+	err := m.source.SetToken(t)
+	if err == nil {
+		return nil
+	}
+	err = fmt.Errorf("storing tokens: %w", err)
+	if l := m.client.Options.logger(); l != nil {
+		l.Warn("samplify storing tokens failed", "error", err)
+	}
+	if b := m.client.Options.BackgroundRefresh; b != nil && b.OnError != nil {
+		b.OnError(err)
+	}
+	return err
+}
+
+// valid returns tokens with an unexpired access token, renewing them first if
+// needed.
+func (m *tokenManager) valid(ctx context.Context) (*TokenResponse, error) {
+	t := m.token()
+	if !t.AccessTokenExpired() {
+		return t, nil
+	}
+	return m.renew(ctx, t, renewAuto)
+}
+
+// renew replaces stale with newly acquired tokens. If the current tokens no
+// longer are stale, another goroutine has already renewed them and they are
+// returned as is. If a renewal is in flight, renew waits for its result
+// instead of starting another one.
+func (m *tokenManager) renew(ctx context.Context, stale *TokenResponse, mode renewMode) (*TokenResponse, error) {
+	for {
+		m.mu.Lock()
+		if t := m.token(); t != stale {
+			m.mu.Unlock()
+			return t, nil
+		}
+		call := m.inflight
+		if call == nil {
+			call = &tokenCall{done: make(chan struct{})}
+			m.inflight = call
+			m.mu.Unlock()
+			m.do(ctx, call, stale, mode)
+			return call.token, call.err
+		}
+		m.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		// The goroutine that started the call gave up on it; try again with
+		// our own context.
+		if isContextErr(call.err) && ctx.Err() == nil {
+			continue
+		}
+		return call.token, call.err
+	}
+}
+
+// do performs call and publishes its result to the waiting goroutines.
+func (m *tokenManager) do(ctx context.Context, call *tokenCall, stale *TokenResponse, mode renewMode) {
+	fetched, used := true, mode
+	defer func() {
+		m.mu.Lock()
+		if call.err == nil {
+			m.swap(call.token)
+		}
+		m.inflight = nil
+		m.mu.Unlock()
+		if call.err == nil && fetched {
+			m.store(call.token)
+		}
+		m.observe(used, fetched, call.err)
+		close(call.done)
+	}()
+
+	switch mode {
+	case renewRefresh:
+		call.token, call.err = m.refresh(ctx, stale)
+	case renewLogin:
+		call.token, call.err = m.login(ctx, stale)
+	default:
+		// Another client sharing the token source may have renewed the
+		// tokens already.
+		if t := m.stored(stale); t != nil {
+			call.token, fetched = t, false
+			return
+		}
+		used = renewRefresh
+		call.token, call.err = m.refresh(ctx, stale)
+		if call.err != nil {
+			used = renewLogin
+			call.token, call.err = m.login(ctx, stale)
+		}
+	}
+}
+
+// observe records the outcome of a renewal with the client's logger and
+// metrics, if any.
+func (m *tokenManager) observe(mode renewMode, fetched bool, err error) {
+	name := mode.String()
+	if !fetched {
+		name = "stored"
+	}
+	if metrics := m.client.Options.Metrics; metrics != nil {
+		metrics.ObserveTokenRenewal(name, err)
+	}
+	l := m.client.Options.logger()
+	if l == nil {
+		return
+	}
+	if err != nil {
+		l.Warn("samplify token renewal failed", "mode", name, "error", err)
+		return
+	}
+	l.Info("samplify tokens renewed", "mode", name)
+}
+
+// stored returns the tokens in the token source if they differ from stale and
+// their access token has not expired.
+func (m *tokenManager) stored(stale *TokenResponse) *TokenResponse {
+	t, err := m.source.Token()
+	if err != nil || t == nil ||
+		t.AccessToken == stale.AccessToken || t.AccessTokenExpired() {
+		return nil
+	}
+	return t
+}
+
+// refresh exchanges the refresh token of t for new tokens.
+func (m *tokenManager) refresh(ctx context.Context, t *TokenResponse) (*TokenResponse, error) {
+	if t.RefreshTokenExpired() {
+		return nil, ErrSessionExpired
+	}
+	c := m.client
+	ctx, op := c.startOperation(ctx, "RefreshToken")
+	defer op.end()
+	acquired := time.Now()
+	req := struct {
+		ClientID     string `json:"clientId"`
+		RefreshToken string `json:"refreshToken"`
+	}{
+		ClientID:     c.Credentials.ClientID,
+		RefreshToken: t.RefreshToken,
+	}
+	ar, err := sendRequest(ctx, c.retryClient, c.Options.AuthURL, "POST", "/token/refresh", "", req)
+	if err == nil {
+		t, err = parseToken(ar, t, acquired)
+	}
+	if err != nil {
+		op.fail(err)
+		return nil, err
+	}
+	return t, nil
+}
+
+// login obtains new tokens with the client's credentials.
+func (m *tokenManager) login(ctx context.Context, t *TokenResponse) (*TokenResponse, error) {
+	c := m.client
+	ctx, op := c.startOperation(ctx, "Login")
+	defer op.end()
+	acquired := time.Now()
+	ar, err := sendRequest(ctx, c.httpClient, c.Options.AuthURL, "POST", "/token/password", "", c.Credentials)
+	if err == nil {
+		t, err = parseToken(ar, t, acquired)
+	}
+	if err == nil && c.Options.CompanyID != 0 {
+		t, err = m.switchCompany(ctx, t, c.Options.CompanyID)
+	}
+	if err != nil {
+		op.fail(err)
+		return nil, err
+	}
+	return t, nil
+}
+
+// switchCompany exchanges the tokens t for tokens of the given company.
+func (m *tokenManager) switchCompany(ctx context.Context, t *TokenResponse, companyID int32) (*TokenResponse, error) {
+	c := m.client
+	ctx, op := c.startOperation(ctx, "SwitchCompany")
+	defer op.end()
+	acquired := time.Now()
+	req := &SwitchCompanyCriteria{
+		ClientID:     c.Credentials.ClientID,
+		RefreshToken: t.RefreshToken,
+		CompanyID:    companyID,
+	}
+	ar, err := sendRequest(ctx, c.httpClient, c.Options.AuthURL, "POST", "/switchCompany", t.AccessToken, req)
+	if err == nil {
+		t, err = parseToken(ar, t, acquired)
+	}
+	if err != nil {
+		op.fail(err)
+		return nil, err
+	}
+	return t, nil
+}
+
+// parseToken decodes the tokens in ar on top of a copy of prev, so that fields
+// missing from the response keep their previous values.
+func parseToken(ar *APIResponse, prev *TokenResponse, acquired time.Time) (*TokenResponse, error) {
+	t := *prev
+	err := json.Unmarshal(ar.Body, &t)
+	if err != nil {
+		return nil, err
+	}
+	t.Acquired = &acquired
+	return &t, nil
+}
+
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// backgroundRetryInterval is how long a backgroundRefresher waits before trying
+// again after a failed refresh.
+const backgroundRetryInterval = 30 * time.Second
+
+// defaultRefreshFraction is the fraction of the access token lifetime after
+// which it is renewed in the background, if not configured otherwise.
+const defaultRefreshFraction = 0.8
+
+// BackgroundRefreshOptions configures renewing the access token in the
+// background, before it expires.
+type BackgroundRefreshOptions struct {
+	// Fraction of the access token lifetime after which it is renewed, for
+	// example 0.8. Values outside (0, 1) select the default of 0.8.
+	Fraction float64
+	// OnError, if not nil, is called when a background refresh fails, and
+	// when renewed tokens cannot be stored in the TokenSource. The client
+	// keeps working; the tokens are renewed by the next request, or kept in
+	// memory only.
+	OnError func(error)
+}
+
+// backgroundRefresher renews the tokens of a tokenManager with the refresh
+// token before the access token expires.
+type backgroundRefresher struct {
+	tokens   *tokenManager
+	fraction float64
+	onError  func(error)
+	cancel   context.CancelFunc
+	done     chan struct{}
+	t        *time.Timer
+}
+
+// startBackgroundRefresh starts renewing the tokens of m in the background
+// until stop is called on the returned refresher.
+func startBackgroundRefresh(m *tokenManager, opts *BackgroundRefreshOptions) *backgroundRefresher {
+	r := &backgroundRefresher{
+		tokens:   m,
+		fraction: opts.Fraction,
+		onError:  opts.OnError,
+		done:     make(chan struct{}),
+	}
+	if r.fraction <= 0 || r.fraction >= 1 {
+		r.fraction = defaultRefreshFraction
+	}
+	var ctx context.Context
+	ctx, r.cancel = context.WithCancel(context.Background())
+	go r.run(ctx)
+	return r
+}
+
+// stop stops the refresher and waits for an in-flight refresh to finish.
+func (r *backgroundRefresher) stop() {
+	r.cancel()
+	<-r.done
+}
+
+func (r *backgroundRefresher) run(ctx context.Context) {
+	defer close(r.done)
+	defer func() {
+		if r.t != nil {
+			r.t.Stop()
+		}
+	}()
+	// after delays the next refresh after a failed one. If negative, the next
+	// refresh waits for the tokens to change.
+	var after time.Duration
+	for {
+		changed := r.tokens.changed()
+		t := r.tokens.token()
+		var due <-chan time.Time
+		if after > 0 {
+			due = r.timer(after)
+		} else if after == 0 && r.refreshable(t) {
+			lifetime := time.Duration(t.ExpiresIn) * time.Second
+			at := t.Acquired.Add(time.Duration(float64(lifetime) * r.fraction))
+			due = r.timer(time.Until(at))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			after = 0
+			continue
+		case <-due:
+		}
+
+		after = 0
+		_, err := r.tokens.renew(ctx, t, renewRefresh)
+		if err == nil || ctx.Err() != nil {
+			continue
+		}
+		if r.onError != nil {
+			r.onError(err)
+		}
+		if err == ErrSessionExpired {
+			// There is nothing to retry with an expired refresh token; wait
+			// for the next request to log in again.
+			after = -1
+		} else {
+			after = backgroundRetryInterval
+		}
+	}
+}
+
+// timer returns a channel that fires after d. The previous timer returned by
+// timer is stopped.
+func (r *backgroundRefresher) timer(d time.Duration) <-chan time.Time {
+	if r.t == nil {
+		r.t = time.NewTimer(d)
+		return r.t.C
+	}
+	if !r.t.Stop() {
+		select {
+		case <-r.t.C:
+		default:
+		}
+	}
+	r.t.Reset(d)
+	return r.t.C
+}
+
+// refreshable reports whether t can be refreshed.
+func (r *backgroundRefresher) refreshable(t *TokenResponse) bool {
+	return len(t.AccessToken) > 0 && len(t.RefreshToken) > 0 && t.Acquired != nil
+}
@@ -0,0 +1,249 @@
+package samplify_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// authServer issues tokens and only accepts requests bearing one of them.
+type authServer struct {
+	*httptest.Server
+	logins      int32
+	refreshes   int32
+	failRefresh int32
+	expiresIn   uint
+
+	mu     sync.Mutex
+	issued map[string]bool
+}
+
+func newAuthServer() *authServer {
+	s := &authServer{issued: map[string]bool{}, expiresIn: 1800}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/auth/token/password", func(w http.ResponseWriter, r *http.Request) {
+		s.issue(w, atomic.AddInt32(&s.logins, 1), "login")
+	})
+	mux.HandleFunc("/auth/token/refresh", func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&s.failRefresh) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		s.issue(w, atomic.AddInt32(&s.refreshes, 1), "refresh")
+	})
+	mux.HandleFunc("/api/", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		ok := s.issued[r.Header.Get("Authorization")]
+		s.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"data":[],"status":{"message":"success"}}`))
+	})
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+func (s *authServer) issue(w http.ResponseWriter, n int32, kind string) {
+	// Widen the window in which concurrent callers could start a second
+	// login or refresh.
+	time.Sleep(20 * time.Millisecond)
+	access := fmt.Sprintf("%s-access-%d", kind, n)
+	s.mu.Lock()
+	s.issued["Bearer "+access] = true
+	s.mu.Unlock()
+	json.NewEncoder(w).Encode(samplify.TokenResponse{
+		AccessToken:      access,
+		ExpiresIn:        s.expiresIn,
+		RefreshToken:     fmt.Sprintf("%s-refresh-%d", kind, n),
+		RefreshExpiresIn: 3600,
+	})
+}
+
+func (s *authServer) client(seed samplify.TokenResponse) *samplify.Client {
+	client := samplify.NewClient("client", "user", "pass", &samplify.ClientOptions{
+		APIBaseURL: s.URL + "/api",
+		AuthURL:    s.URL + "/auth",
+	})
+	client.Auth = seed
+	return client
+}
+
+func hammer(t *testing.T, n int, fn func() error) {
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- fn()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestConcurrentLogin(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	client := s.client(samplify.TokenResponse{})
+
+	hammer(t, 100, func() error {
+		_, err := client.GetAllProjects(nil)
+		return err
+	})
+	if n := atomic.LoadInt32(&s.logins); n != 1 {
+		t.Errorf("expected 1 login, got %d", n)
+	}
+}
+
+func TestCurrentAuth(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	client := s.client(samplify.TokenResponse{})
+
+	if tok := client.CurrentAuth(); tok.AccessToken != "" {
+		t.Errorf("expected no tokens before the first request, got %+v", tok)
+	}
+	if _, err := client.GetAllProjects(nil); err != nil {
+		t.Fatal(err)
+	}
+	first := client.CurrentAuth()
+	if first.AccessToken == "" || client.CurrentAuth() != first {
+		t.Errorf("expected the tokens of the login, got %+v", first)
+	}
+	if n := atomic.LoadInt32(&s.logins); n != 1 {
+		t.Errorf("expected 1 login, got %d", n)
+	}
+}
+
+func TestConcurrentUnauthorized(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	// The token looks valid to the client but the server rejects it.
+	seed := getAuth()
+	seed.AccessToken = "revoked"
+	client := s.client(seed)
+
+	hammer(t, 100, func() error {
+		_, err := client.GetAllProjects(nil)
+		return err
+	})
+	if n := atomic.LoadInt32(&s.logins); n != 1 {
+		t.Errorf("expected 1 login, got %d", n)
+	}
+}
+
+func TestConcurrentRefresh(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	acquired := time.Now().Add(-time.Hour)
+	client := s.client(samplify.TokenResponse{
+		AccessToken:      "expired",
+		ExpiresIn:        60,
+		RefreshToken:     "refresh",
+		RefreshExpiresIn: 7200,
+		Acquired:         &acquired,
+	})
+
+	hammer(t, 100, func() error {
+		_, err := client.GetAllProjects(nil)
+		return err
+	})
+	refreshes, logins := atomic.LoadInt32(&s.refreshes), atomic.LoadInt32(&s.logins)
+	if refreshes != 1 || logins != 0 {
+		t.Errorf("expected 1 refresh and 0 logins, got %d and %d", refreshes, logins)
+	}
+}
+
+func TestConcurrentMixedTokenOperations(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	client := s.client(samplify.TokenResponse{})
+
+	var i int32
+	hammer(t, 200, func() error {
+		switch atomic.AddInt32(&i, 1) % 4 {
+		case 0:
+			_, err := client.GetAuth()
+			return err
+		case 1:
+			if _, err := client.GetAuth(); err != nil {
+				return err
+			}
+			return client.RefreshToken()
+		default:
+			_, err := client.GetAllProjects(nil)
+			return err
+		}
+	})
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	// Renew the 1s tokens every 10ms, so that the test does not wait for
+	// whole seconds.
+	s.expiresIn = 1
+	client := s.client(samplify.TokenResponse{})
+	client.Options.BackgroundRefresh = &samplify.BackgroundRefreshOptions{Fraction: 0.01}
+	if _, err := client.GetAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&s.refreshes) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 background refreshes, got %d", atomic.LoadInt32(&s.refreshes))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Close waits for the refresher to stop, so the count must not change
+	// over several more refresh intervals.
+	client.Close()
+	n := atomic.LoadInt32(&s.refreshes)
+	time.Sleep(50 * time.Millisecond)
+	if m := atomic.LoadInt32(&s.refreshes); m != n {
+		t.Errorf("expected no refreshes after Close, got %d", m-n)
+	}
+}
+
+func TestBackgroundRefreshError(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	s.expiresIn = 1
+	s.failRefresh = 1
+	errs := make(chan error, 1)
+	client := s.client(samplify.TokenResponse{})
+	client.Options.BackgroundRefresh = &samplify.BackgroundRefreshOptions{
+		Fraction: 0.01,
+		OnError:  func(err error) { errs <- err },
+	}
+	defer client.Close()
+	if _, err := client.GetAuth(); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-errs:
+		if _, ok := err.(*samplify.ErrorResponse); !ok {
+			t.Errorf("expected an *ErrorResponse, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a background refresh error")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"mime/multipart"
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -52,12 +53,63 @@ const defaulttimeout = 20
 
 // ClientOptions ...
 type ClientOptions struct {
-	APIBaseURL   string `conform:"trim"`
-	AuthURL      string `conform:"trim"`
-	InternalURL  string `conform:"trim"`
-	StatusURL    string `conform:"trim"`
-	GatewayURL   string `conform:"trim"`
-	Timeout      *int
+	APIBaseURL  string `conform:"trim"`
+	AuthURL     string `conform:"trim"`
+	InternalURL string `conform:"trim"`
+	StatusURL   string `conform:"trim"`
+	GatewayURL  string `conform:"trim"`
+	Timeout     *int
+	// HTTPClient, if not nil, sends the client's requests. Its Timeout, if
+	// zero, is set from Timeout.
+	HTTPClient *http.Client
+	// Transport, if not nil, is the http.RoundTripper that sends the client's
+	// requests. It takes precedence over the transport of HTTPClient.
+	Transport http.RoundTripper
+	// Middleware wraps the transport of every request. The first middleware
+	// is the outermost one, i.e. it sees each request first.
+	Middleware []Middleware
+	// BackgroundRefresh, if not nil, renews the access token in the
+	// background before it expires, until the client is closed.
+	BackgroundRefresh *BackgroundRefreshOptions
+	// Retry, if not nil, retries failed requests according to the policy.
+	Retry *RetryPolicy
+	// RateLimits, if not nil, limits the rate of the requests to each host.
+	// Every attempt of a request waits for the limit of its host. Time spent
+	// waiting counts towards Timeout.
+	RateLimits *RateLimits
+	// MaxInFlight, if positive, limits the number of concurrent requests of
+	// the client across all hosts.
+	MaxInFlight int
+	// SkipStateCheck makes UpdateLineItemState send the change as is. By
+	// default it fetches the line item first, and returns a *TransitionError
+	// without sending the change if the action is not allowed in the current
+	// state. The check costs a request and can race with other changes to
+	// the line item.
+	SkipStateCheck bool
+	// SkipQueryValidation makes list methods send their QueryOptions as is.
+	// By default they check that the options only filter and sort by the
+	// fields the endpoint supports, see SupportedQueryFields, and return a
+	// *QueryFieldError without sending the request otherwise.
+	SkipQueryValidation bool
+	// Logger, if not nil, records every request the client sends, including
+	// retries and token renewals. Secrets such as tokens, passwords and
+	// security keys are redacted.
+	Logger Logger
+	// Metrics, if not nil, records the number, status and duration of the
+	// requests of the client, its retries, token renewals and rate limit
+	// waits.
+	Metrics Metrics
+	// Tracer, if not nil, traces every method of the client in a span named
+	// after the method, e.g. "GetProjectReport", with child spans for its
+	// HTTP requests, retries and token renewals. The trace context is
+	// propagated in the headers of the requests.
+	Tracer Tracer
+	// LogBodies also logs the headers and bodies of requests and responses at
+	// debug level.
+	LogBodies bool
+	// CompanyID, if not zero, is the company the client works for. Every
+	// login is followed by a switch to the company, see SwitchCompany.
+	CompanyID    int32
 	extraOptions *extraOptions
 }
 
@@ -67,8 +119,11 @@ type extraOptions struct {
 	maxRetries   int
 }
 
+// ExtraOption ...
 type ExtraOption func(*extraOptions)
 
+// WithRetry retries failed requests up to the given number of times with the
+// default RetryPolicy. It is ignored if ClientOptions.Retry is set.
 func WithRetry(attempts int) ExtraOption {
 	return func(co *extraOptions) {
 		co.retryEnabled = true
@@ -88,28 +143,67 @@ func initExtraOptions(opts []ExtraOption) *extraOptions {
 }
 
 // Client is used to make API requests to the Samplify API.
+// A Client is safe for concurrent use by multiple goroutines.
 type Client struct {
 	Credentials TokenRequest
-	Auth        TokenResponse
-	Options     *ClientOptions
+	// Auth seeds the tokens used by the client. It is read once, before the
+	// first request, and is not updated afterwards.
+	//
+	// Deprecated: use CurrentAuth to obtain the current tokens.
+	Auth    TokenResponse
+	Options *ClientOptions
+	// TokenSource stores the tokens used by the client. If nil, tokens are
+	// kept in memory, starting with Auth.
+	TokenSource TokenSource
+
+	initOnce    sync.Once
+	closeOnce   sync.Once
+	httpClient  *http.Client
+	retryClient *http.Client
+	tokens      *tokenManager
+	refresher   *backgroundRefresher
+}
+
+// init sets up the client's internal state on first use. Changes to Options
+// after the first request have no effect on the transport.
+func (c *Client) init() {
+	c.initOnce.Do(func() {
+		c.httpClient = c.Options.newHTTPClient()
+		c.retryClient = c.Options.newRetryClient(c.httpClient)
+		source := c.TokenSource
+		if source == nil {
+			source = NewMemoryTokenSource(&c.Auth)
+		}
+		c.tokens = newTokenManager(c, source)
+		if c.Options.BackgroundRefresh != nil {
+			c.refresher = startBackgroundRefresh(c.tokens, c.Options.BackgroundRefresh)
+		}
+	})
+}
+
+// Close stops the background work of the client, such as refreshing tokens.
+// It does not log out; requests made after Close still work but no longer
+// benefit from background work.
+func (c *Client) Close() error {
+	c.init()
+	c.closeOnce.Do(func() {
+		if c.refresher != nil {
+			c.refresher.stop()
+		}
+	})
+	return nil
 }
 
 // GetOrderDetailsWithContext ...
 func (c *Client) GetOrderDetailsWithContext(ctx context.Context, ordNumber string) (*OrderDetailResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetOrderDetails")
+	defer op.end()
 	path := fmt.Sprintf("/orderdetails/%s/", ordNumber)
-	res := &OrderDetailResponse{}
-	resp, err := c.request(ctx, "GET", c.Options.InternalURL, path, res)
-	if err != nil {
-		if resp != nil {
-			json.Unmarshal(resp.Body, &res)
-		}
-		return nil, err
-	}
-	err = json.Unmarshal(resp.Body, &res)
+	res, err := do[OrderDetailResponse, Envelope[OrderDetail]](ctx, c, "GET", c.Options.InternalURL, path, nil)
 	if err != nil {
 		return nil, err
 	}
-	return res, err
+	return res, nil
 }
 
 // GetOrderDetails ...
@@ -119,20 +213,14 @@ func (c *Client) GetOrderDetails(ordNumber string) (*OrderDetailResponse, error)
 
 // CheckOrderNumberWithContext ...
 func (c *Client) CheckOrderNumberWithContext(ctx context.Context, ordNumber string) (bool, error) {
+	ctx, op := c.startOperation(ctx, "CheckOrderNumber")
+	defer op.end()
 	path := fmt.Sprintf("/orderdetails/check/%s", ordNumber)
-	res := &CheckOrderNumberResponse{}
-	resp, err := c.request(ctx, "GET", c.Options.InternalURL, path, res)
-	if err != nil {
-		if resp != nil {
-			json.Unmarshal(resp.Body, &res)
-		}
-		return false, err
-	}
-	err = json.Unmarshal(resp.Body, &res)
+	res, err := do[CheckOrderNumberResponse, Envelope[CheckOrderNumber]](ctx, c, "GET", c.Options.InternalURL, path, nil)
 	if err != nil {
 		return false, err
 	}
-	return res.CheckOrderNumber.Availability, err
+	return res.CheckOrderNumber.Availability, nil
 }
 
 // CheckOrderNumber ...
@@ -142,6 +230,12 @@ func (c *Client) CheckOrderNumber(ordNumber string) (bool, error) {
 
 // GetInvoicesSummaryWithContext ...
 func (c *Client) GetInvoicesSummaryWithContext(ctx context.Context, options *QueryOptions) (*APIResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetInvoicesSummary")
+	defer op.end()
+	err := c.validateQuery(EndpointInvoicesSummary, options)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	path := fmt.Sprintf("/projects/invoices/summary%s", query2String(options))
 	return c.request(ctx, "GET", c.Options.APIBaseURL, path, nil)
 }
@@ -153,13 +247,14 @@ func (c *Client) GetInvoicesSummary(options *QueryOptions) (*APIResponse, error)
 
 // CreateProjectWithContext ...
 func (c *Client) CreateProjectWithContext(ctx context.Context, project *CreateProjectCriteria) (*ProjectResponse, error) {
+	ctx, op := c.startOperation(ctx, "CreateProject")
+	defer op.end()
 	err := Validate(project)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &ProjectResponse{}
-	err = c.requestAndParseResponse(ctx, "POST", "/projects", project, res)
-	return res, err
+	op.setAttribute(AttrExtProjectID, project.ExtProjectID)
+	return do[ProjectResponse, Envelope[*Project]](ctx, c, "POST", c.Options.APIBaseURL, "/projects", project)
 }
 
 // CreateProject ...
@@ -169,15 +264,16 @@ func (c *Client) CreateProject(project *CreateProjectCriteria) (*ProjectResponse
 
 // UpdateProjectWithContext ...
 func (c *Client) UpdateProjectWithContext(ctx context.Context, project *UpdateProjectCriteria) (*ProjectResponse, error) {
+	ctx, op := c.startOperation(ctx, "UpdateProject")
+	defer op.end()
 
 	err := Validate(project)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &ProjectResponse{}
+	op.setAttribute(AttrExtProjectID, project.ExtProjectID)
 	path := fmt.Sprintf("/projects/%s", project.ExtProjectID)
-	err = c.requestAndParseResponse(ctx, "POST", path, project, res)
-	return res, err
+	return do[ProjectResponse, Envelope[*Project]](ctx, c, "POST", c.Options.APIBaseURL, path, project)
 }
 
 // UpdateProject ...
@@ -187,18 +283,18 @@ func (c *Client) UpdateProject(project *UpdateProjectCriteria) (*ProjectResponse
 
 // BuyProjectWithContext ...
 func (c *Client) BuyProjectWithContext(ctx context.Context, extProjectID string, buy []*BuyProjectCriteria) (*BuyProjectResponse, error) {
+	ctx, op := c.startOperation(ctx, "BuyProject", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = Validate(buy)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &BuyProjectResponse{}
 	path := fmt.Sprintf("/projects/%s/buy", extProjectID)
-	err = c.requestAndParseResponse(ctx, "POST", path, buy, res)
-	return res, err
+	return do[BuyProjectResponse, ListEnvelope[*BuyProjectLineItem]](ctx, c, "POST", c.Options.APIBaseURL, path, buy)
 }
 
 // BuyProject ...
@@ -208,14 +304,14 @@ func (c *Client) BuyProject(extProjectID string, buy []*BuyProjectCriteria) (*Bu
 
 // CloseProjectWithContext ...
 func (c *Client) CloseProjectWithContext(ctx context.Context, extProjectID string) (*CloseProjectResponse, error) {
+	ctx, op := c.startOperation(ctx, "CloseProject", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &CloseProjectResponse{}
 	path := fmt.Sprintf("/projects/%s/close", extProjectID)
-	err = c.requestAndParseResponse(ctx, "POST", path, nil, res)
-	return res, err
+	return do[CloseProjectResponse, Envelope[*ClosedProject]](ctx, c, "POST", c.Options.APIBaseURL, path, nil)
 }
 
 // CloseProject ...
@@ -225,11 +321,15 @@ func (c *Client) CloseProject(extProjectID string) (*CloseProjectResponse, error
 
 // GetAllProjectsWithContext ...
 func (c *Client) GetAllProjectsWithContext(ctx context.Context, options *QueryOptions) (*GetAllProjectsResponse, error) {
-	res := &GetAllProjectsResponse{}
+	ctx, op := c.startOperation(ctx, "GetAllProjects")
+	defer op.end()
+	err := c.validateQuery(EndpointProjects, options)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	query := query2String(options)
 	path := fmt.Sprintf("/projects%s", query)
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[GetAllProjectsResponse, ListEnvelope[*ProjectHeader]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetAllProjects ...
@@ -239,14 +339,14 @@ func (c *Client) GetAllProjects(options *QueryOptions) (*GetAllProjectsResponse,
 
 // GetProjectByWithContext returns project by id
 func (c *Client) GetProjectByWithContext(ctx context.Context, extProjectID string) (*ProjectResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetProjectBy", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &ProjectResponse{}
 	path := fmt.Sprintf("/projects/%s", extProjectID)
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[ProjectResponse, Envelope[*Project]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetProjectBy returns project by id
@@ -256,14 +356,14 @@ func (c *Client) GetProjectBy(extProjectID string) (*ProjectResponse, error) {
 
 // GetProjectReportWithContext returns a project's report based on observed data from actual panelists.
 func (c *Client) GetProjectReportWithContext(ctx context.Context, extProjectID string) (*ProjectReportResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetProjectReport", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &ProjectReportResponse{}
 	path := fmt.Sprintf("/projects/%s/report", extProjectID)
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[ProjectReportResponse, Envelope[*ProjectReport]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetProjectReport returns a project's report based on observed data from actual panelists.
@@ -273,22 +373,22 @@ func (c *Client) GetProjectReport(extProjectID string) (*ProjectReportResponse,
 
 // AddLineItemWithContext ...
 func (c *Client) AddLineItemWithContext(ctx context.Context, extProjectID string, lineItem *CreateLineItemCriteria) (*LineItemResponse, error) {
+	ctx, op := c.startOperation(ctx, "AddLineItem", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = Validate(lineItem)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = ValidateSchedule(&lineItem.DaysInField, lineItem.FieldSchedule)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &LineItemResponse{}
 	path := fmt.Sprintf("/projects/%s/lineItems", extProjectID)
-	err = c.requestAndParseResponse(ctx, "POST", path, lineItem, res)
-	return res, err
+	return do[LineItemResponse, Envelope[*LineItem]](ctx, c, "POST", c.Options.APIBaseURL, path, lineItem)
 }
 
 // AddLineItem ...
@@ -299,23 +399,23 @@ func (c *Client) AddLineItem(extProjectID string, lineItem *CreateLineItemCriter
 // UpdateLineItemWithContext ...
 func (c *Client) UpdateLineItemWithContext(ctx context.Context, extProjectID, extLineItemID string,
 	lineItem *UpdateLineItemCriteria) (*LineItemResponse, error) {
+	ctx, op := c.startOperation(ctx, "UpdateLineItem", AttrExtProjectID, extProjectID, AttrExtLineItemID, extLineItemID)
+	defer op.end()
 
 	err := ValidateNotEmpty(extProjectID, extLineItemID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = Validate(lineItem)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = ValidateSchedule(lineItem.DaysInField, lineItem.FieldSchedule)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &LineItemResponse{}
 	path := fmt.Sprintf("/projects/%s/lineItems/%s", extProjectID, extLineItemID)
-	err = c.requestAndParseResponse(ctx, "POST", path, lineItem, res)
-	return res, err
+	return do[LineItemResponse, Envelope[*LineItem]](ctx, c, "POST", c.Options.APIBaseURL, path, lineItem)
 }
 
 // UpdateLineItem ...
@@ -326,20 +426,37 @@ func (c *Client) UpdateLineItem(extProjectID, extLineItemID string,
 }
 
 // UpdateLineItemStateWithContext ... Changes the state of the line item based on provided action.
+// Unless ClientOptions.SkipStateCheck is set, the line item is fetched first and a
+// *TransitionError is returned if the action is not allowed in its current state.
 func (c *Client) UpdateLineItemStateWithContext(ctx context.Context, extProjectID, extLineItemID string, action Action) (
 	*UpdateLineItemStateResponse, error) {
+	ctx, op := c.startOperation(ctx, "UpdateLineItemState", AttrExtProjectID, extProjectID, AttrExtLineItemID, extLineItemID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID, extLineItemID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = ValidateAction(action)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
+	}
+	if !c.Options.SkipStateCheck {
+		li, err := c.GetLineItemByWithContext(ctx, extProjectID, extLineItemID)
+		if err != nil {
+			return nil, op.fail(err)
+		}
+		if li.Item != nil && !li.Item.State.Allows(action) {
+			return nil, op.fail(&TransitionError{
+				ExtProjectID:  extProjectID,
+				ExtLineItemID: extLineItemID,
+				State:         li.Item.State,
+				Action:        action,
+				Allowed:       li.Item.State.AllowedActions(),
+			})
+		}
 	}
-	res := &UpdateLineItemStateResponse{}
 	path := fmt.Sprintf("/projects/%s/lineItems/%s/%s", extProjectID, extLineItemID, action)
-	err = c.requestAndParseResponse(ctx, "POST", path, nil, res)
-	return res, err
+	return do[UpdateLineItemStateResponse, Envelope[*LineItem]](ctx, c, "POST", c.Options.APIBaseURL, path, nil)
 }
 
 // UpdateLineItemState ... Changes the state of the line item based on provided action.
@@ -381,18 +498,18 @@ func (c *Client) CloseLineItem(pid, lid string) (*UpdateLineItemStateResponse, e
 // SetQuotaCellStatusWithContext ... Changes the state of the line item based on provided action.
 func (c *Client) SetQuotaCellStatusWithContext(ctx context.Context, extProjectID, extLineItemID string, quotaCellID string, action Action) (
 	*QuotaCellResponse, error) {
+	ctx, op := c.startOperation(ctx, "SetQuotaCellStatus", AttrExtProjectID, extProjectID, AttrExtLineItemID, extLineItemID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID, extLineItemID, quotaCellID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = ValidateAction(action)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &QuotaCellResponse{}
 	path := fmt.Sprintf("/projects/%s/lineItems/%s/quotaCells/%s/%s", extProjectID, extLineItemID, quotaCellID, action)
-	err = c.requestAndParseResponse(ctx, "POST", path, nil, res)
-	return res, err
+	return do[QuotaCellResponse, Envelope[QuotaCell]](ctx, c, "POST", c.Options.APIBaseURL, path, nil)
 }
 
 // SetQuotaCellStatus ... Changes the state of the line item based on provided action.
@@ -403,14 +520,18 @@ func (c *Client) SetQuotaCellStatus(extProjectID, extLineItemID string, quotaCel
 
 // GetAllLineItemsWithContext ...
 func (c *Client) GetAllLineItemsWithContext(ctx context.Context, extProjectID string, options *QueryOptions) (*GetAllLineItemsResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetAllLineItems", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
+	}
+	err = c.validateQuery(EndpointLineItems, options)
+	if err != nil {
+		return nil, op.fail(err)
 	}
-	res := &GetAllLineItemsResponse{}
 	path := fmt.Sprintf("/projects/%s/lineItems%s", extProjectID, query2String(options))
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[GetAllLineItemsResponse, ListEnvelope[*LineItemListItem]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetAllLineItems ...
@@ -420,14 +541,14 @@ func (c *Client) GetAllLineItems(extProjectID string, options *QueryOptions) (*G
 
 // GetLineItemByWithContext ...
 func (c *Client) GetLineItemByWithContext(ctx context.Context, extProjectID, extLineItemID string) (*LineItemResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetLineItemBy", AttrExtProjectID, extProjectID, AttrExtLineItemID, extLineItemID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID, extLineItemID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &LineItemResponse{}
 	path := fmt.Sprintf("/projects/%s/lineItems/%s", extProjectID, extLineItemID)
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[LineItemResponse, Envelope[*LineItem]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetLineItemBy ...
@@ -436,31 +557,38 @@ func (c *Client) GetLineItemBy(extProjectID, extLineItemID string) (*LineItemRes
 }
 
 // GetFeasibilityWithContext ... Returns the feasibility for all the line items of the requested project. Takes 20 - 120
-// seconds to execute. Check the `GetFeasibilityResponse.Feasibility.Status` field value to see if it is
+// seconds to execute. Check the `LineItemFeasibility.Feasibility.Status` field value to see if it is
 // FeasibilityStatusReady ("READY") or FeasibilityStatusProcessing ("PROCESSING")
-// If GetFeasibilityResponse.Feasibility.Status == FeasibilityStatusProcessing, call this function again in 2 mins.
+// If LineItemFeasibility.Feasibility.Status == FeasibilityStatusProcessing, call this function again in 2 mins,
+// or use WaitForFeasibility.
 func (c *Client) GetFeasibilityWithContext(ctx context.Context, extProjectID string, options *QueryOptions) (*GetFeasibilityResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetFeasibility", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
+	}
+	err = c.validateQuery(EndpointFeasibility, options)
+	if err != nil {
+		return nil, op.fail(err)
 	}
-	res := &GetFeasibilityResponse{}
 	path := fmt.Sprintf("/projects/%s/feasibility%s", extProjectID, query2String(options))
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-
-	return res, err
+	return do[GetFeasibilityResponse, ListEnvelope[*LineItemFeasibility]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetFeasibility ... Returns the feasibility for all the line items of the requested project. Takes 20 - 120
-// seconds to execute. Check the `GetFeasibilityResponse.Feasibility.Status` field value to see if it is
+// seconds to execute. Check the `LineItemFeasibility.Feasibility.Status` field value to see if it is
 // FeasibilityStatusReady ("READY") or FeasibilityStatusProcessing ("PROCESSING")
-// If GetFeasibilityResponse.Feasibility.Status == FeasibilityStatusProcessing, call this function again in 2 mins.
+// If LineItemFeasibility.Feasibility.Status == FeasibilityStatusProcessing, call this function again in 2 mins,
+// or use WaitForFeasibility.
 func (c *Client) GetFeasibility(extProjectID string, options *QueryOptions) (*GetFeasibilityResponse, error) {
 	return c.GetFeasibilityWithContext(context.Background(), extProjectID, options)
 }
 
 // GetInvoiceWithContext ... Get the invoice of the requested project
 func (c *Client) GetInvoiceWithContext(ctx context.Context, extProjectID string, options *QueryOptions) (*APIResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetInvoice", AttrExtProjectID, extProjectID)
+	defer op.end()
 	path := fmt.Sprintf("/projects/%s/invoices", extProjectID)
 	return c.request(ctx, "GET", c.Options.APIBaseURL, path, nil)
 }
@@ -472,8 +600,16 @@ func (c *Client) GetInvoice(extProjectID string, options *QueryOptions) (*APIRes
 
 // UploadReconcileWithContext ...  Upload the Request correction file
 func (c *Client) UploadReconcileWithContext(ctx context.Context, extProjectID string, file multipart.File, fileName string, message string, options *QueryOptions) (*APIResponse, error) {
+	ctx, op := c.startOperation(ctx, "UploadReconcile", AttrExtProjectID, extProjectID)
+	defer op.end()
+	c.init()
+	tok, err := c.tokens.valid(ctx)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	path := fmt.Sprintf("/projects/%s/reconcile", extProjectID)
-	res, err := sendFormData(ctx, c.Options.APIBaseURL, "POST", path, c.Auth.AccessToken, file, fileName, message, *c.Options.Timeout)
+	res, err := sendFormData(ctx, c.retryClient, c.Options.APIBaseURL, "POST", path, tok.AccessToken, file, fileName, message)
+	op.fail(err)
 	return res, err
 }
 
@@ -484,10 +620,14 @@ func (c *Client) UploadReconcile(extProjectID string, file multipart.File, fileN
 
 // GetCountriesWithContext ... Get the list of supported countries and languages in each country.
 func (c *Client) GetCountriesWithContext(ctx context.Context, options *QueryOptions) (*GetCountriesResponse, error) {
-	res := &GetCountriesResponse{}
+	ctx, op := c.startOperation(ctx, "GetCountries")
+	defer op.end()
+	err := c.validateQuery(EndpointCountries, options)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	path := fmt.Sprintf("/countries%s", query2String(options))
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[GetCountriesResponse, ListEnvelope[*Country]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetCountries ... Get the list of supported countries and languages in each country.
@@ -497,22 +637,26 @@ func (c *Client) GetCountries(options *QueryOptions) (*GetCountriesResponse, err
 
 // GetAttributesWithContext ... Get the list of supported attributes for a country and language. This data is required to build up the Quota Plan.
 func (c *Client) GetAttributesWithContext(ctx context.Context, countryCode, languageCode string, options *QueryOptions) (*GetAttributesResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetAttributes")
+	defer op.end()
 	err := ValidateNotEmpty(countryCode, languageCode)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = IsCountryCodeOrEmpty(countryCode)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
 	err = IsLanguageCodeOrEmpty(languageCode)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
+	}
+	err = c.validateQuery(EndpointAttributes, options)
+	if err != nil {
+		return nil, op.fail(err)
 	}
-	res := &GetAttributesResponse{}
 	path := fmt.Sprintf("/attributes/%s/%s%s", countryCode, languageCode, query2String(options))
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[GetAttributesResponse, ListEnvelope[*Attribute]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetAttributes ... Get the list of supported attributes for a country and language. This data is required to build up the Quota Plan.
@@ -522,10 +666,14 @@ func (c *Client) GetAttributes(countryCode, languageCode string, options *QueryO
 
 // GetSurveyTopicsWithContext ... Get the list of supported Survey Topics for a project. This data is required to setup a project.
 func (c *Client) GetSurveyTopicsWithContext(ctx context.Context, options *QueryOptions) (*GetSurveyTopicsResponse, error) {
-	res := &GetSurveyTopicsResponse{}
+	ctx, op := c.startOperation(ctx, "GetSurveyTopics")
+	defer op.end()
+	err := c.validateQuery(EndpointSurveyTopics, options)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	path := fmt.Sprintf("/categories/surveyTopics%s", query2String(options))
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[GetSurveyTopicsResponse, ListEnvelope[*SurveyTopic]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetSurveyTopics ... Get the list of supported Survey Topics for a project. This data is required to setup a project.
@@ -535,10 +683,14 @@ func (c *Client) GetSurveyTopics(options *QueryOptions) (*GetSurveyTopicsRespons
 
 // GetSourcesWithContext ... Get the list of all the Sample sources
 func (c *Client) GetSourcesWithContext(ctx context.Context, options *QueryOptions) (*GetSampleSourceResponse, error) {
-	res := &GetSampleSourceResponse{}
+	ctx, op := c.startOperation(ctx, "GetSources")
+	defer op.end()
+	err := c.validateQuery(EndpointSources, options)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	path := fmt.Sprintf("/sources%s", query2String(options))
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[GetSampleSourceResponse, ListEnvelope[*SampleSource]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetSources ... Get the list of all the Sample sources
@@ -548,10 +700,14 @@ func (c *Client) GetSources(options *QueryOptions) (*GetSampleSourceResponse, er
 
 // GetEventsWithContext ... Returns the list of all events that have occurred for your company account. Most recent events occur at the top of the list.
 func (c *Client) GetEventsWithContext(ctx context.Context, options *QueryOptions) (*GetEventListResponse, error) {
-	res := &GetEventListResponse{}
+	ctx, op := c.startOperation(ctx, "GetEvents")
+	defer op.end()
+	err := c.validateQuery(EndpointEvents, options)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	path := fmt.Sprintf("/events%s", query2String(options))
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[GetEventListResponse, ListEnvelope[*Event]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetEvents ... Returns the list of all events that have occurred for your company account. Most recent events occur at the top of the list.
@@ -561,10 +717,10 @@ func (c *Client) GetEvents(options *QueryOptions) (*GetEventListResponse, error)
 
 // GetEventByWithContext ... Returns the requested event based on the eventID
 func (c *Client) GetEventByWithContext(ctx context.Context, eventID string) (*GetEventResponse, error) {
-	res := &GetEventResponse{}
+	ctx, op := c.startOperation(ctx, "GetEventBy")
+	defer op.end()
 	path := fmt.Sprintf("/events/%s", eventID)
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[GetEventResponse, Envelope[*Event]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetEventBy ... Returns the requested event based on the eventID
@@ -574,8 +730,10 @@ func (c *Client) GetEventBy(eventID string) (*GetEventResponse, error) {
 
 // AcceptEventWithContext ...
 func (c *Client) AcceptEventWithContext(ctx context.Context, event *Event) error {
+	ctx, op := c.startOperation(ctx, "AcceptEvent")
+	defer op.end()
 	if event.Actions == nil || len(event.Actions.AcceptURL) == 0 {
-		return ErrEventActionNotApplicable
+		return op.fail(ErrEventActionNotApplicable)
 	}
 	_, err := c.request(ctx, "POST", event.Actions.AcceptURL, "", nil)
 	return err
@@ -588,8 +746,10 @@ func (c *Client) AcceptEvent(event *Event) error {
 
 // RejectEventWithContext ...
 func (c *Client) RejectEventWithContext(ctx context.Context, event *Event) error {
+	ctx, op := c.startOperation(ctx, "RejectEvent")
+	defer op.end()
 	if event.Actions == nil || len(event.Actions.RejectURL) == 0 {
-		return ErrEventActionNotApplicable
+		return op.fail(ErrEventActionNotApplicable)
 	}
 	_, err := c.request(ctx, "POST", event.Actions.RejectURL, "", nil)
 	return err
@@ -602,14 +762,14 @@ func (c *Client) RejectEvent(event *Event) error {
 
 // GetDetailedProjectReportWithContext returns a project's detailed report based on observed data from actual panelists.
 func (c *Client) GetDetailedProjectReportWithContext(ctx context.Context, extProjectID string) (*DetailedProjectReportResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetDetailedProjectReport", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &DetailedProjectReportResponse{}
 	path := fmt.Sprintf("/projects/%s/detailedReport", extProjectID)
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[DetailedProjectReportResponse, Envelope[DetailedProjectReport]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetDetailedProjectReport returns a project's detailed report based on observed data from actual panelists.
@@ -619,14 +779,14 @@ func (c *Client) GetDetailedProjectReport(extProjectID string) (*DetailedProject
 
 // GetDetailedLineItemReportWithContext returns a lineitems's report with quota cell level stats based on observed data from actual panelists.
 func (c *Client) GetDetailedLineItemReportWithContext(ctx context.Context, extProjectID, extLineItemID string) (*DetailedLineItemReportResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetDetailedLineItemReport", AttrExtProjectID, extProjectID, AttrExtLineItemID, extLineItemID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &DetailedLineItemReportResponse{}
 	path := fmt.Sprintf("/projects/%s/lineItems/%s/detailedReport", extProjectID, extLineItemID)
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[DetailedLineItemReportResponse, Envelope[DetailedLineItemReport]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetDetailedLineItemReport returns a lineitems's report with quota cell level stats based on observed data from actual panelists.
@@ -636,10 +796,10 @@ func (c *Client) GetDetailedLineItemReport(extProjectID, extLineItemID string) (
 
 // GetUserInfoWithContext gives information about the user that is currently logged in.
 func (c *Client) GetUserInfoWithContext(ctx context.Context) (*UserResponse, error) {
-	res := &UserResponse{}
+	ctx, op := c.startOperation(ctx, "GetUserInfo")
+	defer op.end()
 	path := "/users/info"
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[UserResponse, Envelope[*User]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetUserInfo gives information about the user that is currently logged in.
@@ -649,10 +809,10 @@ func (c *Client) GetUserInfo() (*UserResponse, error) {
 
 // GetUserDetailsWithContext ...
 func (c *Client) GetUserDetailsWithContext(ctx context.Context) (*UserDetailsResponse, error) {
-	res := &UserDetailsResponse{}
+	ctx, op := c.startOperation(ctx, "GetUserDetails")
+	defer op.end()
 	path := "/user"
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[UserDetailsResponse, Envelope[*UserDetails]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetUserDetails ...
@@ -662,10 +822,10 @@ func (c *Client) GetUserDetails() (*UserDetailsResponse, error) {
 
 // CompanyUsersWithContext gives information about the user that is currently logged in.
 func (c *Client) CompanyUsersWithContext(ctx context.Context) (*CompanyUsersResponse, error) {
-	res := &CompanyUsersResponse{}
+	ctx, op := c.startOperation(ctx, "CompanyUsers")
+	defer op.end()
 	path := "/users"
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[CompanyUsersResponse, ListEnvelope[*CompanyUser]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // CompanyUsers gives information about the user that is currently logged in.
@@ -675,10 +835,10 @@ func (c *Client) CompanyUsers() (*CompanyUsersResponse, error) {
 
 // TeamsInfoWithContext gives information about the user that is currently logged in.
 func (c *Client) TeamsInfoWithContext(ctx context.Context) (*TeamsResponse, error) {
-	res := &TeamsResponse{}
+	ctx, op := c.startOperation(ctx, "TeamsInfo")
+	defer op.end()
 	path := "/teams"
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[TeamsResponse, ListEnvelope[*CompanyTeam]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // TeamsInfo gives information about the user that is currently logged in.
@@ -688,10 +848,14 @@ func (c *Client) TeamsInfo() (*TeamsResponse, error) {
 
 // RolesWithContext returns the roles specified in the filter.
 func (c *Client) RolesWithContext(ctx context.Context, options *QueryOptions) (*RolesResponse, error) {
-	res := &RolesResponse{}
+	ctx, op := c.startOperation(ctx, "Roles")
+	defer op.end()
+	err := c.validateQuery(EndpointRoles, options)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	path := fmt.Sprintf("/roles%s", query2String(options))
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[RolesResponse, ListEnvelope[Role]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // Roles returns the roles specified in the filter.
@@ -701,14 +865,14 @@ func (c *Client) Roles(options *QueryOptions) (*RolesResponse, error) {
 
 // ProjectPermissionsWithContext gives information about the user that is currently logged in.
 func (c *Client) ProjectPermissionsWithContext(ctx context.Context, extProjectID string) (*ProjectPermissionsResponse, error) {
+	ctx, op := c.startOperation(ctx, "ProjectPermissions", AttrExtProjectID, extProjectID)
+	defer op.end()
 	err := ValidateNotEmpty(extProjectID)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &ProjectPermissionsResponse{}
 	path := fmt.Sprintf("/projects/%s/permissions", extProjectID)
-	err = c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[ProjectPermissionsResponse, Envelope[*ProjectPermissions]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // ProjectPermissions gives information about the user that is currently logged in.
@@ -718,14 +882,15 @@ func (c *Client) ProjectPermissions(extProjectID string) (*ProjectPermissionsRes
 
 // UpsertProjectPermissionsWithContext gives information about the user that is currently logged in.
 func (c *Client) UpsertProjectPermissionsWithContext(ctx context.Context, permissions *UpsertPermissionsCriteria) (*ProjectPermissionsResponse, error) {
+	ctx, op := c.startOperation(ctx, "UpsertProjectPermissions")
+	defer op.end()
 	err := Validate(permissions)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &ProjectPermissionsResponse{}
+	op.setAttribute(AttrExtProjectID, permissions.ExtProjectID)
 	path := fmt.Sprintf("/projects/%s/permissions", permissions.ExtProjectID)
-	err = c.requestAndParseResponse(ctx, "POST", path, permissions, res)
-	return res, err
+	return do[ProjectPermissionsResponse, Envelope[*ProjectPermissions]](ctx, c, "POST", c.Options.APIBaseURL, path, permissions)
 }
 
 // UpsertProjectPermissions gives information about the user that is currently logged in.
@@ -735,10 +900,10 @@ func (c *Client) UpsertProjectPermissions(permissions *UpsertPermissionsCriteria
 
 // GetStudyMetadataWithContext returns study metadata property info
 func (c *Client) GetStudyMetadataWithContext(ctx context.Context) (*StudyMetadataResponse, error) {
-	res := &StudyMetadataResponse{}
+	ctx, op := c.startOperation(ctx, "GetStudyMetadata")
+	defer op.end()
 	path := "/studyMetadata"
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[StudyMetadataResponse, Envelope[StudyMetadata]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetStudyMetadata returns study metadata property info
@@ -748,13 +913,13 @@ func (c *Client) GetStudyMetadata() (*StudyMetadataResponse, error) {
 
 // CreateTemplateWithContext ...
 func (c *Client) CreateTemplateWithContext(ctx context.Context, template *TemplateCriteria) (*TemplateResponse, error) {
+	ctx, op := c.startOperation(ctx, "CreateTemplate")
+	defer op.end()
 	err := Validate(template)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &TemplateResponse{}
-	err = c.requestAndParseResponse(ctx, "POST", "/templates/quotaPlan", template, res)
-	return res, err
+	return do[TemplateResponse, Envelope[*TemplateData]](ctx, c, "POST", c.Options.APIBaseURL, "/templates/quotaPlan", template)
 }
 
 // CreateTemplate ...
@@ -764,14 +929,14 @@ func (c *Client) CreateTemplate(template *TemplateCriteria) (*TemplateResponse,
 
 // UpdateTemplateWithContext ...
 func (c *Client) UpdateTemplateWithContext(ctx context.Context, id int, template *TemplateCriteria) (*TemplateResponse, error) {
+	ctx, op := c.startOperation(ctx, "UpdateTemplate")
+	defer op.end()
 	err := Validate(template)
 	if err != nil {
-		return nil, err
+		return nil, op.fail(err)
 	}
-	res := &TemplateResponse{}
 	path := fmt.Sprintf("/templates/quotaPlan/%d", id)
-	err = c.requestAndParseResponse(ctx, "POST", path, template, res)
-	return res, err
+	return do[TemplateResponse, Envelope[*TemplateData]](ctx, c, "POST", c.Options.APIBaseURL, path, template)
 }
 
 // UpdateTemplate ...
@@ -781,11 +946,15 @@ func (c *Client) UpdateTemplate(id int, template *TemplateCriteria) (*TemplateRe
 
 // GetTemplateListWithContext ...
 func (c *Client) GetTemplateListWithContext(ctx context.Context, country string, lang string, options *QueryOptions) (*TemplatesResponse, error) {
-	res := &TemplatesResponse{}
+	ctx, op := c.startOperation(ctx, "GetTemplateList")
+	defer op.end()
+	err := c.validateQuery(EndpointTemplates, options)
+	if err != nil {
+		return nil, op.fail(err)
+	}
 	query := query2String(options)
 	path := fmt.Sprintf("/templates/quotaPlan/%s/%s%s", country, lang, query)
-	err := c.requestAndParseResponse(ctx, "GET", path, nil, res)
-	return res, err
+	return do[TemplatesResponse, ListEnvelope[*TemplateData]](ctx, c, "GET", c.Options.APIBaseURL, path, nil)
 }
 
 // GetTemplateList ...
@@ -795,10 +964,10 @@ func (c *Client) GetTemplateList(country string, lang string, options *QueryOpti
 
 // DeleteTemplateWithContext ...
 func (c *Client) DeleteTemplateWithContext(ctx context.Context, id int) (*AppError, error) {
-	res := &AppError{}
+	ctx, op := c.startOperation(ctx, "DeleteTemplate")
+	defer op.end()
 	path := fmt.Sprintf("/templates/quotaPlan/%d", id)
-	err := c.requestAndParseResponse(ctx, "DELETE", path, nil, res)
-	return res, err
+	return do[AppError, Envelope[interface{}]](ctx, c, "DELETE", c.Options.APIBaseURL, path, nil)
 }
 
 // DeleteTemplate ...
@@ -808,16 +977,20 @@ func (c *Client) DeleteTemplate(id int) (*AppError, error) {
 
 // SwitchCompanyWithContext ...
 func (c *Client) SwitchCompanyWithContext(ctx context.Context, criteria *SwitchCompanyCriteria) error {
+	ctx, op := c.startOperation(ctx, "SwitchCompany")
+	defer op.end()
+	t := time.Now()
 	response, err := c.request(ctx, "POST", c.Options.AuthURL, "/switchCompany", criteria)
 	if err != nil {
 		return err
 	}
-	err = json.Unmarshal(response.Body, &c.Auth)
+	tok, err := parseToken(response, c.tokens.token(), t)
 	if err != nil {
-		return err
+		return op.fail(err)
+	}
+	if err := c.tokens.set(tok); err != nil {
+		return op.fail(err)
 	}
-	now := time.Now()
-	c.Auth.Acquired = &now
 	return nil
 }
 
@@ -828,27 +1001,9 @@ func (c *Client) SwitchCompany(criteria *SwitchCompanyCriteria) error {
 
 // RefreshTokenWithContext ...
 func (c *Client) RefreshTokenWithContext(ctx context.Context) error {
-	if c.Auth.RefreshTokenExpired() {
-		return ErrSessionExpired
-	}
-	t := time.Now()
-	req := struct {
-		ClientID     string `json:"clientId"`
-		RefreshToken string `json:"refreshToken"`
-	}{
-		ClientID:     c.Credentials.ClientID,
-		RefreshToken: c.Auth.RefreshToken,
-	}
-	ar, err := sendRequest(ctx, c.Options.AuthURL, "POST", "/token/refresh", "", req, *c.Options.Timeout, false, c.Options.extraOptions)
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(ar.Body, &c.Auth)
-	if err != nil {
-		return err
-	}
-	c.Auth.Acquired = &t
-	return nil
+	c.init()
+	_, err := c.tokens.renew(ctx, c.tokens.token(), renewRefresh)
+	return err
 }
 
 // RefreshToken ...
@@ -858,7 +1013,11 @@ func (c *Client) RefreshToken() error {
 
 // LogoutWithContext ...
 func (c *Client) LogoutWithContext(ctx context.Context) error {
-	if c.Auth.AccessTokenExpired() {
+	ctx, op := c.startOperation(ctx, "Logout")
+	defer op.end()
+	c.init()
+	tok := c.tokens.token()
+	if tok.AccessTokenExpired() {
 		return nil
 	}
 	req := struct {
@@ -867,10 +1026,11 @@ func (c *Client) LogoutWithContext(ctx context.Context) error {
 		AccessToken  string `json:"accessToken"`
 	}{
 		ClientID:     c.Credentials.ClientID,
-		RefreshToken: c.Auth.RefreshToken,
-		AccessToken:  c.Auth.AccessToken,
+		RefreshToken: tok.RefreshToken,
+		AccessToken:  tok.AccessToken,
 	}
-	_, err := sendRequest(ctx, c.Options.AuthURL, "POST", "/logout", "", req, *c.Options.Timeout, false, c.Options.extraOptions)
+	_, err := sendRequest(ctx, c.retryClient, c.Options.AuthURL, "POST", "/logout", "", req)
+	op.fail(err)
 	return err
 }
 
@@ -879,79 +1039,72 @@ func (c *Client) Logout() error {
 	return c.LogoutWithContext(context.Background())
 }
 
-// GetAuthWithContext ...
+// CurrentAuth returns the tokens the client currently holds, without logging
+// in or refreshing them. Before the first request, they are the tokens of
+// TokenSource or Auth.
+func (c *Client) CurrentAuth() TokenResponse {
+	c.init()
+	return *c.tokens.token()
+}
+
+// GetAuthWithContext logs in with the client's credentials and returns the
+// acquired tokens.
 func (c *Client) GetAuthWithContext(ctx context.Context) (TokenResponse, error) {
-	err := c.requestAndParseToken(ctx)
+	c.init()
+	tok, err := c.tokens.renew(ctx, c.tokens.token(), renewLogin)
 	if err != nil {
 		return TokenResponse{}, err
 	}
-	return c.Auth, err
+	return *tok, err
 }
 
-// GetAuth ...
+// GetAuth logs in with the client's credentials and returns the acquired
+// tokens.
 func (c *Client) GetAuth() (TokenResponse, error) {
 	return c.GetAuthWithContext(context.Background())
 }
 
-func (c *Client) requestAndParseResponse(ctx context.Context, method, url string, body interface{}, resObj interface{}) error {
-	ar, err := c.request(ctx, method, c.Options.APIBaseURL, url, body)
-	if err != nil {
-		if ar != nil {
-			json.Unmarshal(ar.Body, &resObj)
-		}
-		return err
-	}
-	err = json.Unmarshal(ar.Body, &resObj)
-	if err != nil {
-		return err
-	}
-	return nil
-}
-
 func (c *Client) request(ctx context.Context, method, host, url string, body interface{}) (*APIResponse, error) {
-	err := c.validateTokens(ctx)
+	c.init()
+	tok, err := c.tokens.valid(ctx)
 	if err != nil {
+		operationFrom(ctx).fail(err)
 		return nil, err
 	}
-	ar, err := sendRequest(ctx, host, method, url, c.Auth.AccessToken, body, *c.Options.Timeout, false, c.Options.extraOptions)
+	ar, err := sendRequest(ctx, c.retryClient, host, method, url, tok.AccessToken, body)
 	errResp, ok := err.(*ErrorResponse)
 	if ok && errResp.HTTPCode == http.StatusUnauthorized {
-		err := c.requestAndParseToken(ctx)
+		tok, err = c.tokens.renew(ctx, tok, renewAuto)
 		if err != nil {
+			operationFrom(ctx).fail(err)
 			return nil, err
 		}
-		return sendRequest(ctx, host, method, url, c.Auth.AccessToken, body, *c.Options.Timeout, false, c.Options.extraOptions)
+		ar, err = sendRequest(ctx, c.retryClient, host, method, url, tok.AccessToken, body)
 	}
+	operationFrom(ctx).fail(err)
 	return ar, err
 }
 
-func (c *Client) requestAndParseToken(ctx context.Context) error {
-	// log.WithFields(log.Fields{"module": "go-samplifyapi-client", "function": "requestAndParseToken", "ClientID": c.Credentials.ClientID}).Info()
-	t := time.Now()
-	ar, err := sendRequest(ctx, c.Options.AuthURL, "POST", "/token/password", "", c.Credentials, *c.Options.Timeout, true, c.Options.extraOptions)
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(ar.Body, &c.Auth)
-	if err != nil {
-		return err
-	}
-	c.Auth.Acquired = &t
-	return nil
-}
-
-// ValidateTokens ...
-func (c *Client) validateTokens(ctx context.Context) error {
-	if c.Auth.AccessTokenExpired() {
-		err := c.RefreshTokenWithContext(ctx)
-		if err != nil {
-			err := c.requestAndParseToken(ctx)
-			if err != nil {
-				return err
-			}
+// do sends a request to the API at host, decodes the response into the
+// Envelope or ListEnvelope E and returns it as R, one of the response types
+// of this package. On an API error, the returned R holds the decoded error
+// body, if any, along with the error.
+func do[R, E any, PR interface {
+	*R
+	enveloped[E]
+}](ctx context.Context, c *Client, method, host, path string, body interface{}) (*R, error) {
+	res, env := new(R), new(E)
+	ar, err := c.request(ctx, method, host, path, body)
+	switch {
+	case err != nil && ar != nil && len(ar.Body) > 0:
+		if derr := json.Unmarshal(ar.Body, env); derr != nil {
+			err = fmt.Errorf("%w (decoding the error body: %v)", err, derr)
 		}
+	case err == nil:
+		err = json.Unmarshal(ar.Body, env)
 	}
-	return nil
+	PR(res).setEnvelope(env)
+	return res, err
 }
 
 // NewClient returns an API client.
@@ -992,6 +1145,10 @@ func (c *Client) SetOptions(env string, timeout int) error {
 	if c.Options == nil {
 		return ErrIncorrectEnvironemt
 	}
+	// Copy the options so that changes made through the client do not affect
+	// the predefined options shared by all clients.
+	options := *c.Options
+	c.Options = &options
 
 	if timeout == 0 {
 		timeout = defaulttimeout
@@ -1012,8 +1169,11 @@ func NewClientFromEnv(clientID, username, passsword string, env string, timeout
 		},
 	}
 	err := client.SetOptions(env, timeout)
+	if err != nil {
+		return nil, err
+	}
 	client.Options.extraOptions = initExtraOptions(opts)
-	return client, err
+	return client, nil
 }
 
 // GetHealthyStatus ... Get the healthy status on API
@@ -1022,5 +1182,7 @@ func (c *Client) GetHealthyStatus() (*APIResponse, error) {
 }
 
 func (c *Client) GetHealthyStatusWithContext(ctx context.Context) (*APIResponse, error) {
+	ctx, op := c.startOperation(ctx, "GetHealthyStatus")
+	defer op.end()
 	return c.request(ctx, "GET", c.Options.GatewayURL, "", nil)
 }
@@ -1,8 +1,10 @@
 package samplify_test
 
 import (
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -74,6 +76,7 @@ func TestClientFunctions(t *testing.T) {
 	client := samplify.NewClient("", "", "", nil)
 	client.Options.APIBaseURL = ts.URL
 	client.Options.AuthURL = ts.URL
+	client.Options.SkipStateCheck = true
 	client.Auth = getAuth()
 
 	client.CreateProject(getProjectCriteria())
@@ -120,23 +123,23 @@ func TestQueryString(t *testing.T) {
 		query       *samplify.QueryOptions
 	}{
 		{
-			expectedURL: "/projects?title=Samplify+Client+Test&amp;state=PROVISIONED",
+			expectedURL: "/projects?state=PROVISIONED&title=Samplify+Client+Test",
 			query:       getQueryOptionsOne(),
 		},
 		{
-			expectedURL: "/projects?sort=createdAt:asc,extProjectId:desc",
+			expectedURL: "/projects?sort=createdAt%3Aasc%2CextProjectId%3Adesc",
 			query:       getQueryOptionsTwo(),
 		},
 		{
-			expectedURL: "/projects?title=Samplify+Client+Test&amp;state=PROVISIONED&amp;sort=createdAt:asc,extProjectId:desc",
+			expectedURL: "/projects?sort=createdAt%3Aasc%2CextProjectId%3Adesc&state=PROVISIONED&title=Samplify+Client+Test",
 			query:       getQueryOptionsThree(),
 		},
 		{
-			expectedURL: "/projects?createdAt=2018/11/01,2019/01/01",
+			expectedURL: "/projects?createdAt=2018%2F11%2F01%2C2019%2F01%2F01",
 			query:       getQueryOptionsFour(),
 		},
 		{
-			expectedURL: "/projects?startDate=2019-06-12&amp;endDate=2019-06-19&amp;extProjectId=test-project-id",
+			expectedURL: "/projects?endDate=2019-06-19&extProjectId=test-project-id&startDate=2019-06-12",
 			query:       getQueryOptionsInvoicesSummary(),
 		},
 	}
@@ -150,12 +153,13 @@ func TestQueryString(t *testing.T) {
 		client := samplify.NewClient("", "", "", nil)
 		client.Options.APIBaseURL = ts.URL
 		client.Options.AuthURL = ts.URL
+		client.Options.SkipQueryValidation = true
 		client.Auth = getAuth()
 		client.GetAllProjects(tt.query)
 		ts.Close()
 
 		if url != tt.expectedURL {
-			t.FailNow()
+			t.Fatalf("expected %s, got %s", tt.expectedURL, url)
 		}
 	}
 }
@@ -311,3 +315,35 @@ func getBuyProjectCriteria() []*samplify.BuyProjectCriteria {
 		},
 	}
 }
+
+func TestErrorResponseBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"data":null,"status":{"message":"fail","errors":[{"code":"PROJECT_NOT_FOUND"}]}}`))
+	}))
+	defer ts.Close()
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+	client.Auth = getAuth()
+
+	// The body of a failed request is decoded along with the error.
+	res, err := client.GetProjectBy("nope")
+	if err == nil {
+		t.Fatal("expected an error for an unknown project")
+	}
+	if res == nil || res.Project != nil || res.ResponseStatus.Get() != samplify.StatusTypeFail ||
+		len(res.ResponseStatus.Errors) != 1 || res.ResponseStatus.Errors[0].Code != "PROJECT_NOT_FOUND" {
+		t.Errorf("unexpected error response %+v", res)
+	}
+
+	// A body that cannot be decoded is reported along with the error.
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`<html>Bad Gateway</html>`))
+	}))
+	defer ts.Close()
+	client.Options.APIBaseURL = ts.URL
+	_, err = client.GetProjectBy("p1")
+	if !errors.Is(err, samplify.ErrServer) || !strings.Contains(err.Error(), "decoding the error body") {
+		t.Errorf("expected a server error noting the undecodable body, got %v", err)
+	}
+}
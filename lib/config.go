@@ -0,0 +1,330 @@
+package samplify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Environment variables read by ProfileFromEnv and LoadProfile
+const (
+	EnvConfig      = "SAMPLIFY_CONFIG"
+	EnvProfile     = "SAMPLIFY_PROFILE"
+	EnvEnvironment = "SAMPLIFY_ENV"
+	EnvClientID    = "SAMPLIFY_CLIENT_ID"
+	EnvUsername    = "SAMPLIFY_USERNAME"
+	EnvPassword    = "SAMPLIFY_PASSWORD"
+	EnvAPIBaseURL  = "SAMPLIFY_API_BASE_URL"
+	EnvAuthURL     = "SAMPLIFY_AUTH_URL"
+	EnvInternalURL = "SAMPLIFY_INTERNAL_URL"
+	EnvStatusURL   = "SAMPLIFY_STATUS_URL"
+	EnvGatewayURL  = "SAMPLIFY_GATEWAY_URL"
+	EnvTimeout     = "SAMPLIFY_TIMEOUT"
+	EnvRetries     = "SAMPLIFY_RETRIES"
+	EnvCompanyID   = "SAMPLIFY_COMPANY_ID"
+)
+
+// DefaultProfile is the name of the profile used when none is selected and
+// the config does not name a default.
+const DefaultProfile = "default"
+
+// ErrUnknownProfile ... Returned by Config.Profile and LoadProfile for a
+// profile that is not in the config. Matched with errors.Is.
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// Config is a set of named profiles, usually loaded from a file with
+// LoadConfig. In YAML:
+//
+//	default: uat
+//	profiles:
+//	  uat:
+//	    env: uat
+//	    clientId: my-client
+//	    username: api-user
+//	    timeout: 30
+//	    retries: 3
+//	  local:
+//	    env: local
+//	    apiBaseURL: http://localhost:9000/sample/v1
+//	    authURL: http://localhost:9000/auth/v1
+//
+// The same keys are used in JSON files.
+type Config struct {
+	// Default is the name of the profile used when none is selected.
+	// Defaults to DefaultProfile.
+	Default  string              `json:"default" yaml:"default"`
+	Profiles map[string]*Profile `json:"profiles" yaml:"profiles"`
+}
+
+// Profile holds the settings of a Client. Unset fields keep their defaults:
+// the URLs are those of the profile's Env and Timeout is 20 seconds.
+type Profile struct {
+	// Env is one of local, dev, uat or prod. Defaults to uat.
+	Env      string `json:"env,omitempty" yaml:"env,omitempty"`
+	ClientID string `json:"clientId,omitempty" yaml:"clientId,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	// Password is best left out of config files and set with the
+	// SAMPLIFY_PASSWORD environment variable instead.
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	// The URLs, if set, override those of Env.
+	APIBaseURL  string `json:"apiBaseURL,omitempty" yaml:"apiBaseURL,omitempty"`
+	AuthURL     string `json:"authURL,omitempty" yaml:"authURL,omitempty"`
+	InternalURL string `json:"internalURL,omitempty" yaml:"internalURL,omitempty"`
+	StatusURL   string `json:"statusURL,omitempty" yaml:"statusURL,omitempty"`
+	GatewayURL  string `json:"gatewayURL,omitempty" yaml:"gatewayURL,omitempty"`
+	// Timeout of the requests in seconds.
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// Retries is the number of times failed requests are retried with the
+	// default RetryPolicy.
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// CompanyID, if not zero, is the company the client works for, see
+	// ClientOptions.CompanyID.
+	CompanyID int32 `json:"companyId,omitempty" yaml:"companyId,omitempty"`
+}
+
+// DefaultConfigPath returns the path of the config file used when
+// SAMPLIFY_CONFIG is not set: .samplify/config.yaml in the home directory.
+func DefaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".samplify", "config.yaml")
+}
+
+// LoadConfig reads the config file at path. Files with a .json extension are
+// decoded as JSON, all others as YAML. Unknown keys are rejected so that typos
+// do not go unnoticed.
+func LoadConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.DisallowUnknownFields()
+		err = d.Decode(c)
+	} else {
+		err = yaml.UnmarshalStrict(b, c)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Profile returns a copy of the named profile. If name is empty, the config's
+// default profile is returned.
+func (c *Config) Profile(name string) (*Profile, error) {
+	if len(name) == 0 {
+		name = c.Default
+	}
+	if len(name) == 0 {
+		name = DefaultProfile
+	}
+	p, ok := c.Profiles[name]
+	if !ok || p == nil {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProfile, name)
+	}
+	profile := *p
+	return &profile, nil
+}
+
+// ProfileFromEnv returns the profile set by the SAMPLIFY_* environment
+// variables, such as SAMPLIFY_ENV, SAMPLIFY_CLIENT_ID and SAMPLIFY_TIMEOUT.
+// Variables that are not set leave their fields empty.
+func ProfileFromEnv() (*Profile, error) {
+	p := &Profile{
+		Env:         os.Getenv(EnvEnvironment),
+		ClientID:    os.Getenv(EnvClientID),
+		Username:    os.Getenv(EnvUsername),
+		Password:    os.Getenv(EnvPassword),
+		APIBaseURL:  os.Getenv(EnvAPIBaseURL),
+		AuthURL:     os.Getenv(EnvAuthURL),
+		InternalURL: os.Getenv(EnvInternalURL),
+		StatusURL:   os.Getenv(EnvStatusURL),
+		GatewayURL:  os.Getenv(EnvGatewayURL),
+	}
+	ints := []struct {
+		name string
+		dst  *int
+	}{
+		{EnvTimeout, &p.Timeout},
+		{EnvRetries, &p.Retries},
+	}
+	for _, i := range ints {
+		if v := os.Getenv(i.name); len(v) > 0 {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", i.name, err)
+			}
+			*i.dst = n
+		}
+	}
+	if v := os.Getenv(EnvCompanyID); len(v) > 0 {
+		n, err := strconv.ParseInt(v, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvCompanyID, err)
+		}
+		p.CompanyID = int32(n)
+	}
+	return p, nil
+}
+
+// LoadProfile returns the named profile with the SAMPLIFY_* environment
+// variables applied on top of it. Settings are taken, from highest to lowest
+// precedence, from:
+//
+//  1. the environment variables, see ProfileFromEnv
+//  2. the profile, read from the file named by SAMPLIFY_CONFIG or else from
+//     DefaultConfigPath if it exists
+//  3. the defaults of the profile's Env
+//
+// If name is empty, the profile named by SAMPLIFY_PROFILE or else the
+// config's default profile is used. Without a config file, only the
+// environment variables apply, unless a profile was asked for by name.
+// Explicit settings, such as command-line flags, can be applied on top of the
+// result with Merge.
+func LoadProfile(name string) (*Profile, error) {
+	if len(name) == 0 {
+		name = os.Getenv(EnvProfile)
+	}
+	path := os.Getenv(EnvConfig)
+	if len(path) == 0 {
+		path = DefaultConfigPath()
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			path = ""
+		}
+	}
+
+	p := &Profile{}
+	if len(path) > 0 {
+		c, err := LoadConfig(path)
+		if err != nil {
+			return nil, err
+		}
+		if p, err = c.Profile(name); err != nil {
+			return nil, err
+		}
+	} else if len(name) > 0 {
+		return nil, fmt.Errorf("%w %q: no config file", ErrUnknownProfile, name)
+	}
+
+	env, err := ProfileFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	p.Merge(env)
+	return p, nil
+}
+
+// Merge sets the fields of p to those of o that are not empty.
+func (p *Profile) Merge(o *Profile) {
+	strs := []struct {
+		dst *string
+		src string
+	}{
+		{&p.Env, o.Env},
+		{&p.ClientID, o.ClientID},
+		{&p.Username, o.Username},
+		{&p.Password, o.Password},
+		{&p.APIBaseURL, o.APIBaseURL},
+		{&p.AuthURL, o.AuthURL},
+		{&p.InternalURL, o.InternalURL},
+		{&p.StatusURL, o.StatusURL},
+		{&p.GatewayURL, o.GatewayURL},
+	}
+	for _, s := range strs {
+		if len(s.src) > 0 {
+			*s.dst = s.src
+		}
+	}
+	if o.Timeout != 0 {
+		p.Timeout = o.Timeout
+	}
+	if o.Retries != 0 {
+		p.Retries = o.Retries
+	}
+	if o.CompanyID != 0 {
+		p.CompanyID = o.CompanyID
+	}
+}
+
+// ClientOptions returns the options of a client with the settings of the
+// profile. It returns ErrIncorrectEnvironemt if Env is not one of local, dev,
+// uat or prod.
+func (p *Profile) ClientOptions() (*ClientOptions, error) {
+	var env *ClientOptions
+	switch p.Env {
+	case "local":
+		env = LocalClientOptions
+	case "dev":
+		env = DevClientOptions
+	case "uat", "":
+		env = UATClientOptions
+	case "prod":
+		env = ProdClientOptions
+	default:
+		return nil, ErrIncorrectEnvironemt
+	}
+	o := &ClientOptions{
+		APIBaseURL:  env.APIBaseURL,
+		AuthURL:     env.AuthURL,
+		InternalURL: env.InternalURL,
+		StatusURL:   env.StatusURL,
+		GatewayURL:  env.GatewayURL,
+		CompanyID:   p.CompanyID,
+	}
+	urls := []struct {
+		dst *string
+		src string
+	}{
+		{&o.APIBaseURL, p.APIBaseURL},
+		{&o.AuthURL, p.AuthURL},
+		{&o.InternalURL, p.InternalURL},
+		{&o.StatusURL, p.StatusURL},
+		{&o.GatewayURL, p.GatewayURL},
+	}
+	for _, u := range urls {
+		if len(u.src) > 0 {
+			*u.dst = strings.TrimRight(u.src, "/")
+		}
+	}
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaulttimeout
+	}
+	o.Timeout = &timeout
+	if p.Retries > 0 {
+		o.Retry = &RetryPolicy{MaxAttempts: p.Retries + 1}
+	}
+	return o, nil
+}
+
+// NewClient returns a client with the settings of the profile.
+func (p *Profile) NewClient() (*Client, error) {
+	options, err := p.ClientOptions()
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(p.ClientID, p.Username, p.Password, options), nil
+}
+
+// NewClientFromProfile returns a client with the settings of the named
+// profile, as loaded by LoadProfile.
+func NewClientFromProfile(name string) (*Client, error) {
+	p, err := LoadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.NewClient()
+}
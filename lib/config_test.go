@@ -0,0 +1,144 @@
+package samplify_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+	"github.com/researchnow/go-samplifyapi-client/lib/samplifytest"
+)
+
+const testConfig = `
+default: uat
+profiles:
+  uat:
+    env: uat
+    clientId: uat-client
+    username: uat-user
+    retries: 2
+  fake:
+    env: local
+    clientId: api
+    username: api-user@example.com
+    apiBaseURL: %[1]s/sample/v1/
+    authURL: %[1]s/auth/v1
+    companyId: 42
+`
+
+// setenv sets the environment variables in vars, with empty values unset, and
+// returns a function that restores them.
+func setenv(vars map[string]string) func() {
+	old := map[string]*string{}
+	for k, v := range vars {
+		if prev, ok := os.LookupEnv(k); ok {
+			old[k] = &prev
+		} else {
+			old[k] = nil
+		}
+		if len(v) > 0 {
+			os.Setenv(k, v)
+		} else {
+			os.Unsetenv(k)
+		}
+	}
+	return func() {
+		for k, v := range old {
+			if v != nil {
+				os.Setenv(k, *v)
+			} else {
+				os.Unsetenv(k)
+			}
+		}
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	s.SetCredentials("api", "api-user@example.com", samplifytest.Password)
+	dir, err := ioutil.TempDir("", "samplify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yaml")
+	config := strings.Replace(testConfig, "%[1]s", s.URL, -1)
+	if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	defer setenv(map[string]string{
+		samplify.EnvConfig:   path,
+		samplify.EnvProfile:  "",
+		samplify.EnvPassword: samplifytest.Password,
+		samplify.EnvTimeout:  "7",
+		samplify.EnvClientID: "",
+		samplify.EnvUsername: "",
+		samplify.EnvRetries:  "",
+	})()
+
+	p, err := samplify.LoadProfile("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ClientID != "uat-client" || p.Password != samplifytest.Password || p.Timeout != 7 || p.Retries != 2 {
+		t.Errorf("unexpected default profile %+v", p)
+	}
+	p.Merge(&samplify.Profile{Username: "flag-user", Timeout: 9})
+	if p.Username != "flag-user" || p.Timeout != 9 || p.ClientID != "uat-client" {
+		t.Errorf("unexpected merged profile %+v", p)
+	}
+	opts, err := p.ClientOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.APIBaseURL != samplify.UATClientOptions.APIBaseURL || *opts.Timeout != 9 || opts.Retry.MaxAttempts != 3 {
+		t.Errorf("unexpected options %+v", opts)
+	}
+
+	if _, err := samplify.LoadProfile("staging"); !errors.Is(err, samplify.ErrUnknownProfile) {
+		t.Errorf("expected ErrUnknownProfile, got %v", err)
+	}
+
+	restore := setenv(map[string]string{samplify.EnvProfile: "fake", samplify.EnvEnvironment: "qa"})
+	_, err = samplify.NewClientFromProfile("")
+	if err != samplify.ErrIncorrectEnvironemt {
+		t.Errorf("expected ErrIncorrectEnvironemt, got %v", err)
+	}
+	os.Unsetenv(samplify.EnvEnvironment)
+	client, err := samplify.NewClientFromProfile("")
+	restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Options.APIBaseURL != s.URL+samplifytest.APIPrefix || client.Options.CompanyID != 42 {
+		t.Errorf("unexpected options %+v", client.Options)
+	}
+	if _, err := client.GetAllProjects(nil); err != nil {
+		t.Fatal(err)
+	}
+	reqs := s.Requests()
+	if len(reqs) < 2 || reqs[0] != "POST /token/password" || reqs[1] != "POST /switchCompany" {
+		t.Errorf("expected a login followed by a company switch, got %v", reqs)
+	}
+}
+
+func TestLoadConfigRejectsUnknownKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "samplify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(path, []byte(`{"profiles": {"default": {"env": "dev", "timout": 5}}}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := samplify.LoadConfig(path); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
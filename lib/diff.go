@@ -0,0 +1,525 @@
+package samplify
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeType ...
+type ChangeType string
+
+// ChangeType values
+const (
+	ChangeAdded    ChangeType = "added"
+	ChangeRemoved  ChangeType = "removed"
+	ChangeModified ChangeType = "modified"
+)
+
+// Change is a difference between two versions of a value.
+type Change struct {
+	Type ChangeType
+	// Path locates the value with its JSON field names. Quota groups and
+	// cells are keyed by their QuotaGroupID and QuotaCellID, or by their
+	// name and quota nodes if they have no ID, and filters by their
+	// attribute, e.g. "quotaPlan.quotaGroups[g1].quotaCells[c2].perc".
+	Path string
+	// From is the removed or previous value, To the added or new one.
+	From interface{}
+	To   interface{}
+}
+
+// String returns c as "+ path: to", "- path: from" or "~ path: from -> to".
+func (c *Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Path, formatChangeValue(c.To))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Path, formatChangeValue(c.From))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, formatChangeValue(c.From), formatChangeValue(c.To))
+	}
+}
+
+// formatChangeValue returns v in JSON, or its string representation if it
+// cannot be encoded.
+func formatChangeValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// Changes lists the differences between two versions of a value.
+type Changes []*Change
+
+// String returns the changes one per line, or "no changes".
+func (c Changes) String() string {
+	if len(c) == 0 {
+		return "no changes"
+	}
+	lines := make([]string, len(c))
+	for i, ch := range c {
+		lines[i] = ch.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *Changes) add(t ChangeType, path string, from, to interface{}) {
+	*c = append(*c, &Change{Type: t, Path: path, From: from, To: to})
+}
+
+// DiffQuotaPlan returns the filters, quota groups and quota cells added to,
+// removed from or modified in from to obtain to. Groups and cells are matched
+// by ID and, if to has no ID, by name and quota nodes, so that a plan built
+// locally can be compared with the plan returned by the API. A nil cell
+// status in to is not compared.
+func DiffQuotaPlan(from, to *QuotaPlan) Changes {
+	var c Changes
+	diffQuotaPlan(&c, "", from, to)
+	return c
+}
+
+func diffQuotaPlan(c *Changes, prefix string, from, to *QuotaPlan) {
+	if from == nil {
+		from = &QuotaPlan{}
+	}
+	if to == nil {
+		to = &QuotaPlan{}
+	}
+	diffFilters(c, prefix+"filters", nonNil(from.Filters), nonNil(to.Filters))
+
+	fromGroups, toGroups := nonNil(from.QuotaGroups), nonNil(to.QuotaGroups)
+	toFrom, matched := match(fromGroups, toGroups, quotaGroupID, quotaGroupName)
+	for i, g := range fromGroups {
+		if !matched[i] {
+			c.add(ChangeRemoved, fmt.Sprintf("%squotaGroups[%s]", prefix, quotaGroupKey(g)), g, nil)
+		}
+	}
+	for j, g := range toGroups {
+		if toFrom[j] < 0 {
+			c.add(ChangeAdded, fmt.Sprintf("%squotaGroups[%s]", prefix, quotaGroupKey(g)), nil, g)
+			continue
+		}
+		old := fromGroups[toFrom[j]]
+		key := quotaGroupKey(g)
+		if quotaGroupID(g) == "" {
+			key = quotaGroupKey(old)
+		}
+		path := fmt.Sprintf("%squotaGroups[%s]", prefix, key)
+		if g.Name != nil && quotaGroupName(old) != *g.Name {
+			c.add(ChangeModified, path+".name", old.Name, g.Name)
+		}
+		diffQuotaCells(c, path+".quotaCells", nonNil(old.QuotaCells), nonNil(g.QuotaCells))
+	}
+}
+
+func diffFilters(c *Changes, path string, from, to []*QuotaFilters) {
+	attr := func(f *QuotaFilters) string { return f.AttributeID }
+	toFrom, matched := match(from, to, func(*QuotaFilters) string { return "" }, attr)
+	for i, f := range from {
+		if !matched[i] {
+			c.add(ChangeRemoved, fmt.Sprintf("%s[%s]", path, f.AttributeID), f, nil)
+		}
+	}
+	for j, f := range to {
+		p := fmt.Sprintf("%s[%s]", path, f.AttributeID)
+		if toFrom[j] < 0 {
+			c.add(ChangeAdded, p, nil, f)
+			continue
+		}
+		old := from[toFrom[j]]
+		if !sameOptions(old.Options, f.Options) {
+			c.add(ChangeModified, p+".options", old.Options, f.Options)
+		}
+		if operatorOf(old) != operatorOf(f) {
+			c.add(ChangeModified, p+".operator", old.Operator, f.Operator)
+		}
+	}
+}
+
+func diffQuotaCells(c *Changes, path string, from, to []*QuotaCell) {
+	toFrom, matched := match(from, to, quotaCellID, quotaNodesKey)
+	for i, cell := range from {
+		if !matched[i] {
+			c.add(ChangeRemoved, fmt.Sprintf("%s[%s]", path, quotaCellKey(cell)), cell, nil)
+		}
+	}
+	for j, cell := range to {
+		if toFrom[j] < 0 {
+			c.add(ChangeAdded, fmt.Sprintf("%s[%s]", path, quotaCellKey(cell)), nil, cell)
+			continue
+		}
+		old := from[toFrom[j]]
+		key := quotaCellKey(cell)
+		if quotaCellID(cell) == "" {
+			key = quotaCellKey(old)
+		}
+		p := fmt.Sprintf("%s[%s]", path, key)
+		if quotaNodesKey(old) != quotaNodesKey(cell) {
+			c.add(ChangeModified, p+".quotaNodes", old.QuotaNodes, cell.QuotaNodes)
+		}
+		if !reflect.DeepEqual(old.Perc, cell.Perc) {
+			c.add(ChangeModified, p+".perc", old.Perc, cell.Perc)
+		}
+		if !reflect.DeepEqual(old.Count, cell.Count) {
+			c.add(ChangeModified, p+".count", old.Count, cell.Count)
+		}
+		if cell.Status != nil && !reflect.DeepEqual(old.Status, cell.Status) {
+			c.add(ChangeModified, p+".status", old.Status, cell.Status)
+		}
+	}
+}
+
+// match pairs the items of from and to with the same non-empty ID, then the
+// remaining items of to without an ID with those of from with the same key.
+// It returns the index of the match of each item of to in from, or -1, and
+// whether each item of from was matched.
+func match[T any](from, to []T, id, key func(T) string) ([]int, []bool) {
+	toFrom := make([]int, len(to))
+	matched := make([]bool, len(from))
+	for j, t := range to {
+		toFrom[j] = -1
+		if tid := id(t); tid != "" {
+			for i, f := range from {
+				if !matched[i] && id(f) == tid {
+					toFrom[j], matched[i] = i, true
+					break
+				}
+			}
+		}
+	}
+	for j, t := range to {
+		if toFrom[j] >= 0 || id(t) != "" {
+			continue
+		}
+		for i, f := range from {
+			if !matched[i] && key(f) == key(t) {
+				toFrom[j], matched[i] = i, true
+				break
+			}
+		}
+	}
+	return toFrom, matched
+}
+
+// nonNil returns the non-nil items of s.
+func nonNil[T any](s []*T) []*T {
+	var r []*T
+	for _, v := range s {
+		if v != nil {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+func quotaGroupID(g *QuotaGroup) string {
+	if g.QuotaGroupID == nil {
+		return ""
+	}
+	return *g.QuotaGroupID
+}
+
+func quotaGroupName(g *QuotaGroup) string {
+	if g.Name == nil {
+		return ""
+	}
+	return *g.Name
+}
+
+func quotaGroupKey(g *QuotaGroup) string {
+	if id := quotaGroupID(g); id != "" {
+		return id
+	}
+	return fmt.Sprintf("%q", quotaGroupName(g))
+}
+
+func quotaCellID(c *QuotaCell) string {
+	if c.QuotaCellID == nil {
+		return ""
+	}
+	return *c.QuotaCellID
+}
+
+func quotaCellKey(c *QuotaCell) string {
+	if id := quotaCellID(c); id != "" {
+		return id
+	}
+	return quotaNodesKey(c)
+}
+
+// quotaNodesKey identifies the respondents of a cell, e.g. "11=1&12=18-24,25-34",
+// regardless of the order of its nodes and options.
+func quotaNodesKey(c *QuotaCell) string {
+	nodes := make([]string, 0, len(c.QuotaNodes))
+	for _, n := range c.QuotaNodes {
+		if n != nil {
+			nodes = append(nodes, n.AttributeID+"="+strings.Join(sortedStrings(n.Options), ","))
+		}
+	}
+	sort.Strings(nodes)
+	return strings.Join(nodes, "&")
+}
+
+func operatorOf(f *QuotaFilters) Operator {
+	if f.Operator == nil {
+		return ""
+	}
+	return *f.Operator
+}
+
+func sameOptions(a, b []string) bool {
+	return reflect.DeepEqual(sortedStrings(a), sortedStrings(b))
+}
+
+func sortedStrings(s []string) []string {
+	r := append([]string{}, s...)
+	sort.Strings(r)
+	return r
+}
+
+// LineItemDiff is the difference between a line item and an update of it,
+// see DiffLineItem.
+type LineItemDiff struct {
+	ExtLineItemID string
+	Changes       Changes
+
+	current *LineItem
+	update  *UpdateLineItemCriteria
+	fields  map[string]bool // JSON names of the changed fields
+}
+
+// DiffLineItem returns the changes that sending update would make to the
+// line item current. The fields of update that are nil are not compared; an
+// empty list is compared, as it clears the list.
+// SurveyTestURLParams, which line items do not return, is a change whenever
+// it is set. A nil update changes nothing.
+func DiffLineItem(current *LineItem, update *UpdateLineItemCriteria) *LineItemDiff {
+	d := &LineItemDiff{
+		current: current,
+		update:  update,
+		fields:  map[string]bool{},
+	}
+	if current == nil {
+		current = &LineItem{}
+	}
+	if update == nil {
+		d.ExtLineItemID = current.ExtLineItemID
+		return d
+	}
+	d.ExtLineItemID = update.ExtLineItemID
+	if update.Title != nil {
+		d.compare("title", current.Title, *update.Title)
+	}
+	if update.CountryISOCode != nil {
+		d.compare("countryISOCode", current.CountryISOCode, *update.CountryISOCode)
+	}
+	if update.LanguageISOCode != nil {
+		d.compare("languageISOCode", current.LanguageISOCode, *update.LanguageISOCode)
+	}
+	if update.SurveyURL != nil {
+		d.compare("surveyURL", current.SurveyURL, *update.SurveyURL)
+	}
+	if update.SurveyTestURL != nil {
+		d.compare("surveyTestURL", current.SurveyTestURL, *update.SurveyTestURL)
+	}
+	if update.IndicativeIncidence != nil {
+		d.compare("indicativeIncidence", current.IndicativeIncidence, *update.IndicativeIncidence)
+	}
+	if update.DaysInField != nil {
+		d.compare("daysInField", current.DaysInField, *update.DaysInField)
+	}
+	if update.FieldSchedule != nil {
+		d.compare("fieldSchedule", current.FieldSchedule, update.FieldSchedule)
+	}
+	if update.LengthOfInterview != nil {
+		d.compare("lengthOfInterview", current.LengthOfInterview, *update.LengthOfInterview)
+	}
+	if update.DeliveryType != nil {
+		d.compare("deliveryType", current.DeliveryType, update.DeliveryType)
+	}
+	if update.RequiredCompletes != nil {
+		d.compare("requiredCompletes", current.RequiredCompletes, *update.RequiredCompletes)
+	}
+	if update.QuotaPlan != nil {
+		n := len(d.Changes)
+		diffQuotaPlan(&d.Changes, "quotaPlan.", current.QuotaPlan, update.QuotaPlan)
+		if len(d.Changes) > n {
+			d.fields["quotaPlan"] = true
+		}
+	}
+	if update.SurveyURLParams != nil {
+		d.compare("surveyURLParams", current.SurveyURLParams, update.SurveyURLParams)
+	}
+	if update.SurveyTestURLParams != nil {
+		d.compare("surveyTestURLParams", nil, update.SurveyTestURLParams)
+	}
+	if update.Sources != nil {
+		d.compare("sources", sourceIDs(current.Sources), sourceIDs(*update.Sources))
+	}
+	if update.Targets != nil {
+		d.compare("targets", current.Targets, update.Targets)
+	}
+	if update.SurveyTestingNotes != nil {
+		d.compare("surveyTestingNotes", current.SurveyTestingNotes, *update.SurveyTestingNotes)
+	}
+	return d
+}
+
+// compare records a change of the field if from and to differ.
+func (d *LineItemDiff) compare(field string, from, to interface{}) {
+	if reflect.DeepEqual(from, to) {
+		return
+	}
+	d.fields[field] = true
+	d.Changes.add(ChangeModified, field, from, to)
+}
+
+func sourceIDs(sources []*LineItemSource) []int64 {
+	ids := []int64{}
+	for _, s := range sources {
+		if s != nil {
+			ids = append(ids, s.ID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Empty reports whether the update changes nothing.
+func (d *LineItemDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// String returns the changes one per line, or "no changes".
+func (d *LineItemDiff) String() string {
+	return d.Changes.String()
+}
+
+// Criteria returns an UpdateLineItemCriteria that only sends the changed
+// fields of the update, or nil if nothing changed. The quota plan is sent
+// whole if it changed, with the IDs of the groups and cells of the current
+// plan they were matched with. The days in field or field schedule, which
+// UpdateLineItem requires, are always set, from the update or else from the
+// current line item.
+func (d *LineItemDiff) Criteria() *UpdateLineItemCriteria {
+	if d.Empty() {
+		return nil
+	}
+	u := d.update
+	c := &UpdateLineItemCriteria{ExtLineItemID: d.ExtLineItemID}
+	for field := range d.fields {
+		switch field {
+		case "title":
+			c.Title = u.Title
+		case "countryISOCode":
+			c.CountryISOCode = u.CountryISOCode
+		case "languageISOCode":
+			c.LanguageISOCode = u.LanguageISOCode
+		case "surveyURL":
+			c.SurveyURL = u.SurveyURL
+		case "surveyTestURL":
+			c.SurveyTestURL = u.SurveyTestURL
+		case "indicativeIncidence":
+			c.IndicativeIncidence = u.IndicativeIncidence
+		case "daysInField":
+			c.DaysInField = u.DaysInField
+		case "fieldSchedule":
+			c.FieldSchedule = u.FieldSchedule
+		case "lengthOfInterview":
+			c.LengthOfInterview = u.LengthOfInterview
+		case "deliveryType":
+			c.DeliveryType = u.DeliveryType
+		case "requiredCompletes":
+			c.RequiredCompletes = u.RequiredCompletes
+		case "quotaPlan":
+			var current *QuotaPlan
+			if d.current != nil {
+				current = d.current.QuotaPlan
+			}
+			c.QuotaPlan = withQuotaIDs(current, u.QuotaPlan)
+		case "surveyURLParams":
+			c.SurveyURLParams = u.SurveyURLParams
+		case "surveyTestURLParams":
+			c.SurveyTestURLParams = u.SurveyTestURLParams
+		case "sources":
+			c.Sources = u.Sources
+		case "targets":
+			c.Targets = u.Targets
+		case "surveyTestingNotes":
+			c.SurveyTestingNotes = u.SurveyTestingNotes
+		}
+	}
+	if c.DaysInField == nil && c.FieldSchedule == nil {
+		c.DaysInField, c.FieldSchedule = u.DaysInField, u.FieldSchedule
+		if c.DaysInField == nil && c.FieldSchedule == nil && d.current != nil {
+			if d.current.FieldSchedule != nil {
+				c.FieldSchedule = d.current.FieldSchedule
+			} else {
+				days := d.current.DaysInField
+				c.DaysInField = &days
+			}
+		}
+	}
+	c.sent = map[string]bool{"extLineItemId": true, "daysInField": true, "fieldSchedule": c.FieldSchedule != nil}
+	for field := range d.fields {
+		c.sent[field] = true
+	}
+	return c
+}
+
+// MarshalJSON encodes c, with only the changed fields if c was returned by
+// LineItemDiff.Criteria.
+func (c UpdateLineItemCriteria) MarshalJSON() ([]byte, error) {
+	type criteria UpdateLineItemCriteria
+	b, err := json.Marshal(criteria(c))
+	if err != nil || c.sent == nil {
+		return b, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	for field := range fields {
+		if !c.sent[field] {
+			delete(fields, field)
+		}
+	}
+	return json.Marshal(fields)
+}
+
+// withQuotaIDs returns a copy of to whose groups and cells without an ID have
+// the ID of the group or cell of from they match, if any.
+func withQuotaIDs(from, to *QuotaPlan) *QuotaPlan {
+	if from == nil {
+		return to
+	}
+	plan := &QuotaPlan{Filters: to.Filters}
+	fromGroups, toGroups := nonNil(from.QuotaGroups), nonNil(to.QuotaGroups)
+	toFrom, _ := match(fromGroups, toGroups, quotaGroupID, quotaGroupName)
+	for j, g := range toGroups {
+		g := *g
+		if toFrom[j] >= 0 {
+			old := fromGroups[toFrom[j]]
+			if g.QuotaGroupID == nil {
+				g.QuotaGroupID = old.QuotaGroupID
+			}
+			fromCells, toCells := nonNil(old.QuotaCells), nonNil(g.QuotaCells)
+			cellFrom, _ := match(fromCells, toCells, quotaCellID, quotaNodesKey)
+			g.QuotaCells = make([]*QuotaCell, len(toCells))
+			for k, cell := range toCells {
+				cell := *cell
+				if cellFrom[k] >= 0 && cell.QuotaCellID == nil {
+					cell.QuotaCellID = fromCells[cellFrom[k]].QuotaCellID
+				}
+				g.QuotaCells[k] = &cell
+			}
+		}
+		plan.QuotaGroups = append(plan.QuotaGroups, &g)
+	}
+	return plan
+}
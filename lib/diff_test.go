@@ -0,0 +1,132 @@
+package samplify_test
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// remotePlan returns the quota plan of testAttributes as returned by the API,
+// with IDs.
+func remotePlan() *samplify.QuotaPlan {
+	b := samplify.NewQuotaPlanBuilder(testAttributes())
+	b.Filter("AGE", "18-24", "25-34")
+	b.QuotaGroup("Gender").
+		Perc(40, samplify.Select("GENDER", "Male")).
+		Perc(60, samplify.Select("GENDER", "Female"))
+	plan, _ := b.Build()
+	plan.QuotaGroups[0].QuotaGroupID = strptr("g1")
+	plan.QuotaGroups[0].QuotaCells[0].QuotaCellID = strptr("c1")
+	plan.QuotaGroups[0].QuotaCells[1].QuotaCellID = strptr("c2")
+	return plan
+}
+
+func TestDiffQuotaPlan(t *testing.T) {
+	b := samplify.NewQuotaPlanBuilder(testAttributes())
+	b.Filter("AGE", "25-34", "18-24")
+	b.QuotaGroup("Gender").
+		Perc(50, samplify.Select("GENDER", "Female")).
+		Perc(50, samplify.Select("GENDER", "Male"))
+	b.QuotaGroup("Age").
+		Count(100, samplify.Select("AGE", "18-24"))
+	local, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := samplify.DiffQuotaPlan(remotePlan(), local).String()
+	want := strings.Join([]string{
+		`~ quotaGroups[g1].quotaCells[c2].perc: 60 -> 50`,
+		`~ quotaGroups[g1].quotaCells[c1].perc: 40 -> 50`,
+		`+ quotaGroups["Age"]: {"name":"Age","quotaCells":[{"quotaNodes":[{"attributeId":"12","options":["18-24"]}],"count":100}]}`,
+	}, "\n")
+	if got != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", got, want)
+	}
+	if d := samplify.DiffQuotaPlan(remotePlan(), remotePlan()); len(d) != 0 {
+		t.Errorf("expected no changes, got:\n%s", d)
+	}
+}
+
+func TestDiffLineItem(t *testing.T) {
+	title := "Old title"
+	current := &samplify.LineItem{
+		Title:             "Old title",
+		DaysInField:       5,
+		LengthOfInterview: 10,
+		RequiredCompletes: 200,
+		QuotaPlan:         remotePlan(),
+	}
+	current.ExtLineItemID = "lineItem001"
+
+	plan := remotePlan()
+	plan.QuotaGroups[0].QuotaGroupID = nil
+	plan.QuotaGroups[0].QuotaCells[1].QuotaCellID = nil
+	*plan.QuotaGroups[0].QuotaCells[1].Perc = 55
+	*plan.QuotaGroups[0].QuotaCells[0].Perc = 45
+	completes := int64(300)
+	loi := int64(10)
+	update := &samplify.UpdateLineItemCriteria{
+		ExtLineItemID:     "lineItem001",
+		Title:             &title,
+		LengthOfInterview: &loi,
+		RequiredCompletes: &completes,
+		QuotaPlan:         plan,
+	}
+
+	d := samplify.DiffLineItem(current, update)
+	want := strings.Join([]string{
+		`~ requiredCompletes: 200 -> 300`,
+		`~ quotaPlan.quotaGroups[g1].quotaCells[c1].perc: 40 -> 45`,
+		`~ quotaPlan.quotaGroups[g1].quotaCells[c2].perc: 60 -> 55`,
+	}, "\n")
+	if d.String() != want {
+		t.Errorf("unexpected diff:\n%s\nwant:\n%s", d, want)
+	}
+
+	c := d.Criteria()
+	if c.ExtLineItemID != "lineItem001" || c.Title != nil || c.LengthOfInterview != nil || *c.RequiredCompletes != 300 || *c.DaysInField != 5 {
+		t.Errorf("unexpected criteria %+v", c)
+	}
+	g := c.QuotaPlan.QuotaGroups[0]
+	if *g.QuotaGroupID != "g1" || *g.QuotaCells[1].QuotaCellID != "c2" || *g.QuotaCells[1].Perc != 55 {
+		t.Errorf("expected the IDs of the current plan in %+v", g)
+	}
+	if plan.QuotaGroups[0].QuotaGroupID != nil {
+		t.Error("the update was modified")
+	}
+	// Only the changed fields, and the days in field UpdateLineItem requires,
+	// are sent.
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent map[string]interface{}
+	json.Unmarshal(b, &sent)
+	keys := []string{}
+	for k := range sent {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if want := "daysInField extLineItemId quotaPlan requiredCompletes"; strings.Join(keys, " ") != want {
+		t.Errorf("expected only %s to be sent, got %s", want, b)
+	}
+
+	// An empty list clears the current one, and is sent.
+	count := uint32(10)
+	current.Targets = []*samplify.LineItemTarget{{Count: &count}}
+	d = samplify.DiffLineItem(current, &samplify.UpdateLineItemCriteria{ExtLineItemID: "lineItem001", Targets: []*samplify.LineItemTarget{}})
+	if b, _ := json.Marshal(d.Criteria()); d.String() != `~ targets: [{"count":10}] -> []` || string(b) != `{"daysInField":5,"extLineItemId":"lineItem001","targets":[]}` {
+		t.Errorf("expected the targets to be cleared, got %s, %s", d, b)
+	}
+
+	if c := samplify.DiffLineItem(current, &samplify.UpdateLineItemCriteria{ExtLineItemID: "lineItem001", Title: &title}).Criteria(); c != nil {
+		t.Errorf("expected no criteria without changes, got %+v", c)
+	}
+	if d := samplify.DiffLineItem(current, nil); !d.Empty() || d.ExtLineItemID != "lineItem001" || d.Criteria() != nil {
+		t.Errorf("expected an empty diff without an update, got %+v", d)
+	}
+}
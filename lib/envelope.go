@@ -0,0 +1,27 @@
+package samplify
+
+// Envelope is the shape of every API response: the requested object in
+// "data", the outcome in "status" and, for some endpoints, details such as
+// pagination in "meta". The response types of this package, such as
+// ProjectResponse, are decoded from an Envelope and keep their own name for
+// Data; their Envelope method returns the common shape.
+type Envelope[T any] struct {
+	Data           T              `json:"data"`
+	ResponseStatus ResponseStatus `json:"status"`
+	Meta           Meta           `json:"meta"`
+}
+
+// ListEnvelope is the response of an endpoint returning a list, such as
+// GetAllProjects. Meta holds the total number of items and the links to the
+// other pages, see QueryOptions.
+type ListEnvelope[T any] struct {
+	Data           []T            `json:"data"`
+	ResponseStatus ResponseStatus `json:"status"`
+	Meta           Meta           `json:"meta"`
+}
+
+// enveloped is implemented by the response types decoded from the Envelope
+// or ListEnvelope E.
+type enveloped[E any] interface {
+	setEnvelope(e *E)
+}
@@ -0,0 +1,43 @@
+package samplify_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+func TestEnvelope(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/projects":
+			w.Write([]byte(`{"data":[{"extProjectId":"p1"}],"status":{"message":"success"},"meta":{"total":1}}`))
+		case "/templates/quotaPlan/1":
+			w.Write([]byte(`{"data":{"id":1},"status":{"message":"fail","errors":[{"code":"E1","resource":{"id":"1"}}]}}`))
+		}
+	}))
+	defer ts.Close()
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+	client.Auth = getAuth()
+
+	projects, err := client.GetAllProjects(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env *samplify.ListEnvelope[*samplify.ProjectHeader] = projects.Envelope()
+	if len(env.Data) != 1 || env.Data[0] != projects.Projects[0] || env.Meta.Total != 1 ||
+		env.ResponseStatus.Get() != samplify.StatusTypeSuccess {
+		t.Errorf("unexpected envelope %+v", env)
+	}
+
+	// Templates share ResponseStatus and ErrorInfo with the other responses.
+	deleted, err := client.DeleteTemplate(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var info samplify.ErrorInfo = deleted.Status.Errors[0]
+	if info.Code != "E1" || info.Resource.ID != "1" || deleted.Envelope().ResponseStatus.Get() != samplify.StatusTypeFail {
+		t.Errorf("unexpected template status %+v", deleted.Status)
+	}
+}
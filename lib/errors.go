@@ -1,18 +1,42 @@
 package samplify
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 )
 
+// API errors, matched by an *ErrorResponse with errors.Is based on its HTTP status code
+var (
+	// ErrValidation ... The request was rejected as invalid (400, 422)
+	ErrValidation = errors.New("request is invalid")
+	// ErrUnauthorized ... The credentials or tokens were rejected (401)
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden ... The user is not allowed to perform the request (403)
+	ErrForbidden = errors.New("forbidden")
+	// ErrNotFound ... The requested resource does not exist (404)
+	ErrNotFound = errors.New("resource not found")
+	// ErrConflict ... The request conflicts with the state of the resource (409)
+	ErrConflict = errors.New("conflict with the current state of the resource")
+	// ErrRateLimited ... Too many requests were sent (429)
+	ErrRateLimited = errors.New("rate limited")
+	// ErrServer ... The API failed to handle the request (5xx)
+	ErrServer = errors.New("server error")
+)
+
 // Error ...
 type Error struct {
 	Path    string `json:"path"`
 	Message string `json:"message"`
 }
 
-// ErrorResponse ...
+// ErrorResponse is returned for requests that fail with an HTTP error status.
+// Use errors.Is with one of the API errors, such as ErrNotFound, to check the
+// kind of failure and APIErrors or HasCode to branch on the API's own error
+// codes.
 type ErrorResponse struct {
 	Timestamp  *time.Time `json:"timestamp"`
 	RequestID  string     `json:"requestId"`
@@ -20,6 +44,30 @@ type ErrorResponse struct {
 	HTTPCode   int        `json:"httpCode"`
 	HTTPPhrase string     `json:"httpPhrase"`
 	Errors     []*Error   `json:"errors"`
+	// APIErrors are the errors reported in the "status" part of the response
+	// body, if any.
+	APIErrors []ErrorInfo `json:"apiErrors,omitempty"`
+}
+
+// newErrorResponse returns the error for resp, which failed with an HTTP error
+// status, decoding the API errors from its body.
+func newErrorResponse(resp *http.Response, path string, body []byte) *ErrorResponse {
+	t := time.Now()
+	e := &ErrorResponse{
+		Timestamp:  &t,
+		RequestID:  resp.Header.Get("x-request-id"),
+		HTTPCode:   resp.StatusCode,
+		HTTPPhrase: resp.Status,
+		Path:       path,
+		Errors:     []*Error{{Path: path, Message: resp.Status}},
+	}
+	res := struct {
+		Status *ResponseStatus `json:"status"`
+	}{}
+	if json.Unmarshal(body, &res) == nil && res.Status != nil {
+		e.APIErrors = res.Status.Errors
+	}
+	return e
 }
 
 // Error ...
@@ -28,5 +76,57 @@ func (e *ErrorResponse) Error() string {
 	for _, err := range e.Errors {
 		str = fmt.Sprintf("%s\n%s", str, err.Message)
 	}
+	for _, info := range e.APIErrors {
+		if len(info.Code) > 0 {
+			str = fmt.Sprintf("%s\n%s: %s", str, info.Code, info.Message)
+		} else {
+			str = fmt.Sprintf("%s\n%s", str, info.Message)
+		}
+	}
 	return strings.TrimSpace(str)
 }
+
+// Is reports whether target is the API error matching the HTTP status code of e.
+func (e *ErrorResponse) Is(target error) bool {
+	switch target {
+	case ErrValidation:
+		return e.HTTPCode == http.StatusBadRequest || e.HTTPCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.HTTPCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.HTTPCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.HTTPCode == http.StatusNotFound
+	case ErrConflict:
+		return e.HTTPCode == http.StatusConflict
+	case ErrRateLimited:
+		return e.HTTPCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.HTTPCode >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// HasCode reports whether the API reported an error with the given code.
+func (e *ErrorResponse) HasCode(code string) bool {
+	for _, info := range e.APIErrors {
+		if info.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorCodes returns the API error codes of err, if it is or wraps an
+// *ErrorResponse.
+func ErrorCodes(err error) []string {
+	var e *ErrorResponse
+	if !errors.As(err, &e) {
+		return nil
+	}
+	codes := make([]string, 0, len(e.APIErrors))
+	for _, info := range e.APIErrors {
+		codes = append(codes, info.Code)
+	}
+	return codes
+}
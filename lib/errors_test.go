@@ -0,0 +1,79 @@
+package samplify_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+func TestErrorResponse(t *testing.T) {
+	tests := []struct {
+		status   int
+		body     string
+		sentinel error
+		codes    []string
+	}{
+		{
+			status:   http.StatusNotFound,
+			body:     `{"status":{"message":"fail","errors":[{"code":"RESOURCE_NOT_FOUND","message":"project not found","resource":{"id":"p1","type":"project"}}]}}`,
+			sentinel: samplify.ErrNotFound,
+			codes:    []string{"RESOURCE_NOT_FOUND"},
+		},
+		{
+			status:   http.StatusConflict,
+			body:     `{"status":{"message":"fail","errors":[{"code":"A"},{"code":"B"}]}}`,
+			sentinel: samplify.ErrConflict,
+			codes:    []string{"A", "B"},
+		},
+		{
+			status:   http.StatusBadRequest,
+			body:     `not json`,
+			sentinel: samplify.ErrValidation,
+			codes:    []string{},
+		},
+		{
+			status:   http.StatusTooManyRequests,
+			sentinel: samplify.ErrRateLimited,
+			codes:    []string{},
+		},
+		{
+			status:   http.StatusBadGateway,
+			sentinel: samplify.ErrServer,
+			codes:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+			w.Write([]byte(tt.body))
+		}))
+		client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+		client.Auth = getAuth()
+		_, err := client.GetProjectBy("p1")
+		ts.Close()
+
+		if !errors.Is(err, tt.sentinel) {
+			t.Errorf("%d: expected errors.Is(err, %v), got %v", tt.status, tt.sentinel, err)
+		}
+		if errors.Is(err, samplify.ErrUnauthorized) {
+			t.Errorf("%d: unexpected match with ErrUnauthorized", tt.status)
+		}
+		var e *samplify.ErrorResponse
+		if !errors.As(err, &e) || e.HTTPCode != tt.status {
+			t.Errorf("%d: expected an *ErrorResponse, got %v", tt.status, err)
+		}
+		if codes := samplify.ErrorCodes(err); !reflect.DeepEqual(codes, tt.codes) {
+			t.Errorf("%d: expected codes %v, got %v", tt.status, tt.codes, codes)
+		}
+		for _, code := range tt.codes {
+			if !e.HasCode(code) {
+				t.Errorf("%d: expected HasCode(%q)", tt.status, code)
+			}
+		}
+	}
+}
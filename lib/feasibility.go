@@ -0,0 +1,120 @@
+package samplify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrFeasibilityFailed ... Matched by a *FeasibilityError with errors.Is
+var ErrFeasibilityFailed = errors.New("feasibility failed")
+
+// defaultFeasibilityInterval is how long the API asks callers to wait before
+// checking feasibility again.
+const defaultFeasibilityInterval = 2 * time.Minute
+
+// WaitForFeasibilityOptions configure WaitForFeasibility.
+type WaitForFeasibilityOptions struct {
+	// Interval is the delay before the first poll after a PROCESSING
+	// response. Defaults to 2 minutes.
+	Interval time.Duration
+	// Multiplier is the factor by which the delay grows after each poll.
+	// Defaults to 1, i.e. a constant interval.
+	Multiplier float64
+	// MaxInterval, if not zero, caps the delay between polls.
+	MaxInterval time.Duration
+	// Options are passed to GetFeasibility.
+	Options *QueryOptions
+	// OnUpdate, if not nil, is called with every response, including the
+	// final one.
+	OnUpdate func(*GetFeasibilityResponse)
+}
+
+// FeasibilityError is returned by WaitForFeasibility when the feasibility of
+// some line items could not be computed.
+type FeasibilityError struct {
+	ExtProjectID string
+	// ExtLineItemIDs of the line items with status FAILED.
+	ExtLineItemIDs []string
+}
+
+// Error ...
+func (e *FeasibilityError) Error() string {
+	return fmt.Sprintf("feasibility failed for line items %s of project %s",
+		strings.Join(e.ExtLineItemIDs, ", "), e.ExtProjectID)
+}
+
+// Is reports whether target is ErrFeasibilityFailed.
+func (e *FeasibilityError) Is(target error) bool {
+	return target == ErrFeasibilityFailed
+}
+
+// feasibilityDone reports whether no line item of res is still PROCESSING,
+// and returns the line items that FAILED.
+func feasibilityDone(res *GetFeasibilityResponse) (bool, []string) {
+	failed := []string{}
+	for _, item := range res.List {
+		if item == nil {
+			continue
+		}
+		if item.Feasibility == nil {
+			return false, nil
+		}
+		switch item.Feasibility.Status {
+		case FeasibilityStatusReady, FeasibilityStatusNotSupported:
+		case FeasibilityStatusFailed:
+			failed = append(failed, item.ExtLineItemID)
+		default:
+			return false, nil
+		}
+	}
+	return true, failed
+}
+
+// WaitForFeasibility polls GetFeasibility until the feasibility of every line
+// item of the project is READY, NOT_SUPPORTED or FAILED, or ctx is done. It
+// returns the last response, along with a *FeasibilityError if some line
+// items FAILED.
+func (c *Client) WaitForFeasibility(ctx context.Context, extProjectID string, opts *WaitForFeasibilityOptions) (*GetFeasibilityResponse, error) {
+	o := WaitForFeasibilityOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.Interval <= 0 {
+		o.Interval = defaultFeasibilityInterval
+	}
+	if o.Multiplier < 1 {
+		o.Multiplier = 1
+	}
+
+	delay := o.Interval
+	for {
+		res, err := c.GetFeasibilityWithContext(ctx, extProjectID, o.Options)
+		if err != nil {
+			return res, err
+		}
+		if o.OnUpdate != nil {
+			o.OnUpdate(res)
+		}
+		if done, failed := feasibilityDone(res); done {
+			if len(failed) > 0 {
+				return res, &FeasibilityError{ExtProjectID: extProjectID, ExtLineItemIDs: failed}
+			}
+			return res, nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, ctx.Err()
+		case <-timer.C:
+		}
+		delay = time.Duration(float64(delay) * o.Multiplier)
+		if o.MaxInterval > 0 && delay > o.MaxInterval {
+			delay = o.MaxInterval
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package samplify_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+func TestWaitForFeasibility(t *testing.T) {
+	responses := []string{
+		`{"data":[{"extLineItemId":"l1","feasibility":{"status":"PROCESSING"}},{"extLineItemId":"l2","feasibility":{"status":"PROCESSING"}}]}`,
+		`{"data":[{"extLineItemId":"l1","feasibility":{"status":"READY"}},{"extLineItemId":"l2","feasibility":{"status":"PROCESSING"}}]}`,
+		`{"data":[{"extLineItemId":"l1","feasibility":{"status":"READY"}},{"extLineItemId":"l2","feasibility":{"status":"FAILED"}}]}`,
+	}
+	var polls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&polls, 1)
+		w.Write([]byte(responses[n-1]))
+	}))
+	defer ts.Close()
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+	client.Auth = getAuth()
+
+	updates := 0
+	res, err := client.WaitForFeasibility(context.Background(), "p1", &samplify.WaitForFeasibilityOptions{
+		Interval: time.Millisecond,
+		OnUpdate: func(*samplify.GetFeasibilityResponse) { updates++ },
+	})
+	if polls != 3 || updates != 3 {
+		t.Errorf("expected 3 polls and updates, got %d and %d", polls, updates)
+	}
+	if res == nil || len(res.List) != 2 || res.List[0].Feasibility.Status != samplify.FeasibilityStatusReady {
+		t.Errorf("expected the final response, got %+v", res)
+	}
+	var ferr *samplify.FeasibilityError
+	if !errors.Is(err, samplify.ErrFeasibilityFailed) || !errors.As(err, &ferr) ||
+		!reflect.DeepEqual(ferr.ExtLineItemIDs, []string{"l2"}) {
+		t.Errorf("expected a FeasibilityError naming l2, got %v", err)
+	}
+}
+
+func TestWaitForFeasibilityContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":[{"extLineItemId":"l1","feasibility":{"status":"PROCESSING"}}]}`))
+	}))
+	defer ts.Close()
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+	client.Auth = getAuth()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.WaitForFeasibility(ctx, "p1", &samplify.WaitForFeasibilityOptions{Interval: time.Millisecond, Multiplier: 2})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to end with the context, got %v", err)
+	}
+}
@@ -0,0 +1,466 @@
+package samplify
+
+import (
+	"context"
+)
+
+// defaultPageSize is the page size used by iterators when QueryOptions.Limit
+// is not set.
+const defaultPageSize uint = 100
+
+// IteratorOptions bound the pages fetched by an iterator.
+type IteratorOptions struct {
+	// MaxItems, if positive, is the maximum number of items returned.
+	MaxItems int
+	// Prefetch is the number of pages fetched ahead of the current one.
+	Prefetch int
+}
+
+// fetchFunc fetches the page of a list endpoint selected by opts.
+type fetchFunc[T any] func(ctx context.Context, opts *QueryOptions) ([]T, *Meta, error)
+
+// page is the result of fetching a page.
+type page[T any] struct {
+	opts  QueryOptions
+	items []T
+	meta  *Meta
+	err   error
+}
+
+// pageIterator walks the pages of a list endpoint by increasing
+// QueryOptions.Offset until Meta.Total items were returned or, if the total is
+// unknown, until a page is short or has no next link.
+type pageIterator[T any] struct {
+	fetch    fetchFunc[T]
+	opts     QueryOptions
+	maxItems int
+	prefetch int
+
+	pending []chan page[T]
+	start   uint // offset of the first page
+	offset  uint // offset of the next page to fetch
+	total   int  // total number of items, or -1 if unknown
+	last    bool // whether the last page was fetched
+	buf     []T
+	cur     T
+	seen    int
+	err     error
+}
+
+func newPageIterator[T any](fetch fetchFunc[T], options *QueryOptions, it *IteratorOptions) pageIterator[T] {
+	p := pageIterator[T]{fetch: fetch, total: -1}
+	if options != nil {
+		p.opts = *options
+	}
+	if p.opts.Limit == 0 {
+		p.opts.Limit = defaultPageSize
+	}
+	if p.opts.Limit > maxLimit {
+		p.opts.Limit = maxLimit
+	}
+	p.start, p.offset = p.opts.Offset, p.opts.Offset
+	if it != nil {
+		p.maxItems, p.prefetch = it.MaxItems, it.Prefetch
+	}
+	return p
+}
+
+// Next advances the iterator to the next item, fetching pages as needed. It
+// returns false when there are no more items or an error occurred, see Err.
+func (p *pageIterator[T]) Next(ctx context.Context) bool {
+	if p.err != nil || (p.maxItems > 0 && p.seen >= p.maxItems) {
+		return false
+	}
+	for len(p.buf) == 0 {
+		if p.last && len(p.pending) == 0 {
+			return false
+		}
+		if err := p.nextPage(ctx); err != nil {
+			p.err = err
+			return false
+		}
+	}
+	p.cur, p.buf = p.buf[0], p.buf[1:]
+	p.seen++
+	return true
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *pageIterator[T]) Err() error {
+	return p.err
+}
+
+// all returns the remaining items.
+func (p *pageIterator[T]) all(ctx context.Context) ([]T, error) {
+	list := []T{}
+	for p.Next(ctx) {
+		list = append(list, p.cur)
+	}
+	return list, p.Err()
+}
+
+// schedule starts fetching the next page and up to prefetch pages after it.
+func (p *pageIterator[T]) schedule(ctx context.Context) {
+	for !p.last && len(p.pending) <= p.prefetch {
+		if p.maxItems > 0 && p.offset >= p.start+uint(p.maxItems) {
+			p.last = true
+			return
+		}
+		if p.total >= 0 && p.offset >= uint(p.total) {
+			p.last = true
+			return
+		}
+		opts := p.opts
+		opts.Offset = p.offset
+		ch := make(chan page[T], 1)
+		go func() {
+			items, meta, err := p.fetch(ctx, &opts)
+			ch <- page[T]{opts: opts, items: items, meta: meta, err: err}
+		}()
+		p.pending = append(p.pending, ch)
+		p.offset += p.opts.Limit
+	}
+}
+
+// nextPage fills the buffer with the next page.
+func (p *pageIterator[T]) nextPage(ctx context.Context) error {
+	p.schedule(ctx)
+	if len(p.pending) == 0 {
+		return nil
+	}
+	var pg page[T]
+	select {
+	case pg = <-p.pending[0]:
+		p.pending = p.pending[1:]
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if pg.err != nil && isContextErr(pg.err) && ctx.Err() == nil {
+		// The page was prefetched with the context of an earlier call.
+		pg.items, pg.meta, pg.err = p.fetch(ctx, &pg.opts)
+	}
+	if pg.err != nil {
+		return pg.err
+	}
+
+	p.buf = pg.items
+	n := uint(len(pg.items))
+	if pg.meta != nil && pg.meta.Total > 0 {
+		p.total = int(pg.meta.Total)
+	}
+	switch {
+	case n == 0:
+		p.last = true
+	case p.total >= 0 && n < pg.opts.Limit && pg.opts.Offset+n < uint(p.total):
+		// The API returned a smaller page than requested; continue with
+		// its page size.
+		p.opts.Limit = n
+		p.offset = pg.opts.Offset + n
+	case p.total < 0 && (n < pg.opts.Limit || (pg.meta != nil && len(pg.meta.Self) > 0 && len(pg.meta.Next) == 0)):
+		p.last = true
+	default:
+		return nil
+	}
+	// Drop the pages fetched beyond this one.
+	p.pending = nil
+	return nil
+}
+
+// ProjectIterator iterates over the projects returned by GetAllProjects. Call
+// Next to advance to the next project, Project to get it and Err to check for
+// errors once Next returns false.
+type ProjectIterator struct {
+	pageIterator[*ProjectHeader]
+}
+
+// Project returns the current project.
+func (it *ProjectIterator) Project() *ProjectHeader {
+	return it.cur
+}
+
+// ProjectIterator returns an iterator over the projects matching options.
+func (c *Client) ProjectIterator(options *QueryOptions, it *IteratorOptions) *ProjectIterator {
+	return &ProjectIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*ProjectHeader, *Meta, error) {
+		res, err := c.GetAllProjectsWithContext(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.Projects, &res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllProjectsWithContext returns all the projects matching options,
+// fetching as many pages as needed.
+func (c *Client) ListAllProjectsWithContext(ctx context.Context, options *QueryOptions, it *IteratorOptions) ([]*ProjectHeader, error) {
+	return c.ProjectIterator(options, it).all(ctx)
+}
+
+// ListAllProjects ...
+func (c *Client) ListAllProjects(options *QueryOptions, it *IteratorOptions) ([]*ProjectHeader, error) {
+	return c.ListAllProjectsWithContext(context.Background(), options, it)
+}
+
+// LineItemIterator iterates over the line items returned by GetAllLineItems.
+type LineItemIterator struct {
+	pageIterator[*LineItemListItem]
+}
+
+// LineItem returns the current line item.
+func (it *LineItemIterator) LineItem() *LineItemListItem {
+	return it.cur
+}
+
+// LineItemIterator returns an iterator over the line items of a project
+// matching options.
+func (c *Client) LineItemIterator(extProjectID string, options *QueryOptions, it *IteratorOptions) *LineItemIterator {
+	return &LineItemIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*LineItemListItem, *Meta, error) {
+		res, err := c.GetAllLineItemsWithContext(ctx, extProjectID, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.List, &res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllLineItemsWithContext returns all the line items of a project matching
+// options, fetching as many pages as needed.
+func (c *Client) ListAllLineItemsWithContext(ctx context.Context, extProjectID string, options *QueryOptions, it *IteratorOptions) ([]*LineItemListItem, error) {
+	return c.LineItemIterator(extProjectID, options, it).all(ctx)
+}
+
+// ListAllLineItems ...
+func (c *Client) ListAllLineItems(extProjectID string, options *QueryOptions, it *IteratorOptions) ([]*LineItemListItem, error) {
+	return c.ListAllLineItemsWithContext(context.Background(), extProjectID, options, it)
+}
+
+// EventIterator iterates over the events returned by GetEvents.
+type EventIterator struct {
+	pageIterator[*Event]
+}
+
+// Event returns the current event.
+func (it *EventIterator) Event() *Event {
+	return it.cur
+}
+
+// EventIterator returns an iterator over the events matching options.
+func (c *Client) EventIterator(options *QueryOptions, it *IteratorOptions) *EventIterator {
+	return &EventIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*Event, *Meta, error) {
+		res, err := c.GetEventsWithContext(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.List, &res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllEventsWithContext returns all the events matching options, fetching
+// as many pages as needed.
+func (c *Client) ListAllEventsWithContext(ctx context.Context, options *QueryOptions, it *IteratorOptions) ([]*Event, error) {
+	return c.EventIterator(options, it).all(ctx)
+}
+
+// ListAllEvents ...
+func (c *Client) ListAllEvents(options *QueryOptions, it *IteratorOptions) ([]*Event, error) {
+	return c.ListAllEventsWithContext(context.Background(), options, it)
+}
+
+// SourceIterator iterates over the sample sources returned by GetSources.
+type SourceIterator struct {
+	pageIterator[*SampleSource]
+}
+
+// Source returns the current sample source.
+func (it *SourceIterator) Source() *SampleSource {
+	return it.cur
+}
+
+// SourceIterator returns an iterator over the sample sources matching options.
+func (c *Client) SourceIterator(options *QueryOptions, it *IteratorOptions) *SourceIterator {
+	return &SourceIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*SampleSource, *Meta, error) {
+		res, err := c.GetSourcesWithContext(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.List, &res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllSourcesWithContext returns all the sample sources matching options,
+// fetching as many pages as needed.
+func (c *Client) ListAllSourcesWithContext(ctx context.Context, options *QueryOptions, it *IteratorOptions) ([]*SampleSource, error) {
+	return c.SourceIterator(options, it).all(ctx)
+}
+
+// ListAllSources ...
+func (c *Client) ListAllSources(options *QueryOptions, it *IteratorOptions) ([]*SampleSource, error) {
+	return c.ListAllSourcesWithContext(context.Background(), options, it)
+}
+
+// TemplateIterator iterates over the templates returned by GetTemplateList.
+type TemplateIterator struct {
+	pageIterator[*TemplateData]
+}
+
+// Template returns the current template.
+func (it *TemplateIterator) Template() *TemplateData {
+	return it.cur
+}
+
+// TemplateIterator returns an iterator over the quota plan templates of a
+// country and language matching options.
+func (c *Client) TemplateIterator(country string, lang string, options *QueryOptions, it *IteratorOptions) *TemplateIterator {
+	return &TemplateIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*TemplateData, *Meta, error) {
+		res, err := c.GetTemplateListWithContext(ctx, country, lang, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.Data, res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllTemplatesWithContext returns all the quota plan templates of a
+// country and language matching options, fetching as many pages as needed.
+func (c *Client) ListAllTemplatesWithContext(ctx context.Context, country string, lang string, options *QueryOptions, it *IteratorOptions) ([]*TemplateData, error) {
+	return c.TemplateIterator(country, lang, options, it).all(ctx)
+}
+
+// ListAllTemplates ...
+func (c *Client) ListAllTemplates(country string, lang string, options *QueryOptions, it *IteratorOptions) ([]*TemplateData, error) {
+	return c.ListAllTemplatesWithContext(context.Background(), country, lang, options, it)
+}
+
+// CountryIterator iterates over the countries returned by GetCountries.
+type CountryIterator struct {
+	pageIterator[*Country]
+}
+
+// Country returns the current country.
+func (it *CountryIterator) Country() *Country {
+	return it.cur
+}
+
+// CountryIterator returns an iterator over the countries matching options.
+func (c *Client) CountryIterator(options *QueryOptions, it *IteratorOptions) *CountryIterator {
+	return &CountryIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*Country, *Meta, error) {
+		res, err := c.GetCountriesWithContext(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.List, &res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllCountriesWithContext returns all the countries matching options,
+// fetching as many pages as needed.
+func (c *Client) ListAllCountriesWithContext(ctx context.Context, options *QueryOptions, it *IteratorOptions) ([]*Country, error) {
+	return c.CountryIterator(options, it).all(ctx)
+}
+
+// ListAllCountries ...
+func (c *Client) ListAllCountries(options *QueryOptions, it *IteratorOptions) ([]*Country, error) {
+	return c.ListAllCountriesWithContext(context.Background(), options, it)
+}
+
+// AttributeIterator iterates over the attributes returned by GetAttributes.
+type AttributeIterator struct {
+	pageIterator[*Attribute]
+}
+
+// Attribute returns the current attribute.
+func (it *AttributeIterator) Attribute() *Attribute {
+	return it.cur
+}
+
+// AttributeIterator returns an iterator over the attributes of a country and
+// language matching options.
+func (c *Client) AttributeIterator(countryCode, languageCode string, options *QueryOptions, it *IteratorOptions) *AttributeIterator {
+	return &AttributeIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*Attribute, *Meta, error) {
+		res, err := c.GetAttributesWithContext(ctx, countryCode, languageCode, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.List, &res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllAttributesWithContext returns all the attributes of a country and
+// language matching options, fetching as many pages as needed.
+func (c *Client) ListAllAttributesWithContext(ctx context.Context, countryCode, languageCode string, options *QueryOptions, it *IteratorOptions) ([]*Attribute, error) {
+	return c.AttributeIterator(countryCode, languageCode, options, it).all(ctx)
+}
+
+// ListAllAttributes ...
+func (c *Client) ListAllAttributes(countryCode, languageCode string, options *QueryOptions, it *IteratorOptions) ([]*Attribute, error) {
+	return c.ListAllAttributesWithContext(context.Background(), countryCode, languageCode, options, it)
+}
+
+// SurveyTopicIterator iterates over the survey topics returned by
+// GetSurveyTopics.
+type SurveyTopicIterator struct {
+	pageIterator[*SurveyTopic]
+}
+
+// SurveyTopic returns the current survey topic.
+func (it *SurveyTopicIterator) SurveyTopic() *SurveyTopic {
+	return it.cur
+}
+
+// SurveyTopicIterator returns an iterator over the survey topics matching
+// options.
+func (c *Client) SurveyTopicIterator(options *QueryOptions, it *IteratorOptions) *SurveyTopicIterator {
+	return &SurveyTopicIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*SurveyTopic, *Meta, error) {
+		res, err := c.GetSurveyTopicsWithContext(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return res.List, &res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllSurveyTopicsWithContext returns all the survey topics matching
+// options, fetching as many pages as needed.
+func (c *Client) ListAllSurveyTopicsWithContext(ctx context.Context, options *QueryOptions, it *IteratorOptions) ([]*SurveyTopic, error) {
+	return c.SurveyTopicIterator(options, it).all(ctx)
+}
+
+// ListAllSurveyTopics ...
+func (c *Client) ListAllSurveyTopics(options *QueryOptions, it *IteratorOptions) ([]*SurveyTopic, error) {
+	return c.ListAllSurveyTopicsWithContext(context.Background(), options, it)
+}
+
+// RoleIterator iterates over the roles returned by Roles.
+type RoleIterator struct {
+	pageIterator[*Role]
+}
+
+// Role returns the current role.
+func (it *RoleIterator) Role() *Role {
+	return it.cur
+}
+
+// RoleIterator returns an iterator over the roles matching options.
+func (c *Client) RoleIterator(options *QueryOptions, it *IteratorOptions) *RoleIterator {
+	return &RoleIterator{newPageIterator(func(ctx context.Context, opts *QueryOptions) ([]*Role, *Meta, error) {
+		res, err := c.RolesWithContext(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		items := make([]*Role, len(res.Roles))
+		for i := range res.Roles {
+			items[i] = &res.Roles[i]
+		}
+		return items, &res.Meta, nil
+	}, options, it)}
+}
+
+// ListAllRolesWithContext returns all the roles matching options, fetching as
+// many pages as needed.
+func (c *Client) ListAllRolesWithContext(ctx context.Context, options *QueryOptions, it *IteratorOptions) ([]*Role, error) {
+	return c.RoleIterator(options, it).all(ctx)
+}
+
+// ListAllRoles ...
+func (c *Client) ListAllRoles(options *QueryOptions, it *IteratorOptions) ([]*Role, error) {
+	return c.ListAllRolesWithContext(context.Background(), options, it)
+}
@@ -0,0 +1,108 @@
+package samplify_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// pagedServer serves n projects, paginated by offset and limit. If total is
+// false, it reports the next page in the links instead of the total.
+func pagedServer(n int, total bool) (*httptest.Server, *int32) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		data := []map[string]string{}
+		for i := offset; i < offset+limit && i < n; i++ {
+			data = append(data, map[string]string{"extProjectId": fmt.Sprintf("p%d", i)})
+		}
+		meta := map[string]interface{}{}
+		if total {
+			meta["total"] = n
+		} else {
+			links := map[string]string{"self": r.URL.String()}
+			if offset+limit < n {
+				links["next"] = fmt.Sprintf("/projects?offset=%d&limit=%d", offset+limit, limit)
+			}
+			meta["links"] = links
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data, "meta": meta})
+	}))
+	return ts, &requests
+}
+
+func TestListAllProjects(t *testing.T) {
+	tests := []struct {
+		total    bool
+		limit    uint
+		it       *samplify.IteratorOptions
+		want     int
+		requests int32
+	}{
+		{total: true, limit: 10, want: 25, requests: 3},
+		{total: false, limit: 10, want: 25, requests: 3},
+		{total: false, limit: 5, want: 25, requests: 5},
+		{total: true, want: 25, requests: 1},
+		{total: true, limit: 10, it: &samplify.IteratorOptions{MaxItems: 12}, want: 12, requests: 2},
+		{total: true, limit: 5, it: &samplify.IteratorOptions{Prefetch: 2}, want: 25, requests: 5},
+	}
+	for i, tt := range tests {
+		ts, requests := pagedServer(25, tt.total)
+		client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+		client.Auth = getAuth()
+
+		list, err := client.ListAllProjects(&samplify.QueryOptions{Limit: tt.limit}, tt.it)
+		ts.Close()
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+		if len(list) != tt.want {
+			t.Errorf("%d: expected %d projects, got %d", i, tt.want, len(list))
+		}
+		for j, p := range list {
+			if p.ExtProjectID != fmt.Sprintf("p%d", j) {
+				t.Errorf("%d: expected project p%d, got %s", i, j, p.ExtProjectID)
+				break
+			}
+		}
+		if n := atomic.LoadInt32(requests); n != tt.requests {
+			t.Errorf("%d: expected %d requests, got %d", i, tt.requests, n)
+		}
+	}
+}
+
+func TestProjectIteratorError(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"data":[{"extProjectId":"p0"},{"extProjectId":"p1"}],"meta":{"total":4}}`))
+	}))
+	defer ts.Close()
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+	client.Auth = getAuth()
+
+	iter := client.ProjectIterator(&samplify.QueryOptions{Limit: 2}, nil)
+	n := 0
+	for iter.Next(context.Background()) {
+		n++
+	}
+	if n != 2 || !errors.Is(iter.Err(), samplify.ErrServer) {
+		t.Errorf("expected 2 projects and ErrServer, got %d and %v", n, iter.Err())
+	}
+	if iter.Next(context.Background()) {
+		t.Error("expected the iterator to stay stopped after an error")
+	}
+}
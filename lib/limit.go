@@ -0,0 +1,196 @@
+package samplify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket that allows Rate requests per second on
+// average, with bursts of up to Burst requests.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimits limits the requests sent to each of the hosts of a Client. A nil
+// RateLimit leaves the requests to its host unlimited.
+type RateLimits struct {
+	// API limits the requests to APIBaseURL.
+	API *RateLimit
+	// Auth limits the requests to AuthURL, including token renewals.
+	Auth *RateLimit
+	// Internal limits the requests to InternalURL.
+	Internal *RateLimit
+	// Status limits the requests to StatusURL and GatewayURL.
+	Status *RateLimit
+}
+
+// bucket is a token bucket. Tokens are reserved ahead of time, so that
+// concurrent waiters are served in the order they arrived.
+type bucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(l *RateLimit) *bucket {
+	burst := float64(l.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{rate: l.Rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// wait blocks until a token is available or ctx is done. It returns how long
+// it waited.
+func (b *bucket) wait(ctx context.Context) (time.Duration, error) {
+	if b.rate <= 0 {
+		return 0, nil
+	}
+	b.mu.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	delay := time.Duration(-b.tokens / b.rate * float64(time.Second))
+	b.mu.Unlock()
+	if delay <= 0 {
+		return 0, nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// Give the reserved token back.
+		b.mu.Lock()
+		b.tokens++
+		b.mu.Unlock()
+		return 0, ctx.Err()
+	case <-timer.C:
+		return delay, nil
+	}
+}
+
+// limitTransport waits for the rate limit of the host of each request and
+// for a free in-flight slot before sending it.
+type limitTransport struct {
+	next http.RoundTripper
+	// buckets maps base URLs to their rate limits.
+	buckets map[string]*bucket
+	// slots has a buffer of MaxInFlight, or is nil if unlimited.
+	slots   chan struct{}
+	metrics Metrics
+}
+
+// newLimitTransport wraps next with the rate limits and MaxInFlight of o, or
+// returns next if there are none.
+func (o *ClientOptions) newLimitTransport(next http.RoundTripper) http.RoundTripper {
+	t := &limitTransport{next: next, buckets: map[string]*bucket{}, metrics: o.Metrics}
+	if l := o.RateLimits; l != nil {
+		for _, h := range []struct {
+			limit *RateLimit
+			urls  []string
+		}{
+			{l.API, []string{o.APIBaseURL}},
+			{l.Auth, []string{o.AuthURL}},
+			{l.Internal, []string{o.InternalURL}},
+			{l.Status, []string{o.StatusURL, o.GatewayURL}},
+		} {
+			if h.limit == nil {
+				continue
+			}
+			b := newBucket(h.limit)
+			for _, u := range h.urls {
+				if len(u) > 0 {
+					t.buckets[u] = b
+				}
+			}
+		}
+	}
+	if o.MaxInFlight > 0 {
+		t.slots = make(chan struct{}, o.MaxInFlight)
+	}
+	if len(t.buckets) == 0 && t.slots == nil {
+		return next
+	}
+	return t
+}
+
+// bucket returns the rate limit of the base URL with the longest match for
+// req, or nil if it has none.
+func (t *limitTransport) bucket(req *http.Request) *bucket {
+	u := req.URL.String()
+	var match string
+	var b *bucket
+	for base, bb := range t.buckets {
+		if strings.HasPrefix(u, base) && len(base) > len(match) {
+			match, b = base, bb
+		}
+	}
+	return b
+}
+
+// RoundTrip sends req once it is allowed to. The in-flight slot is released
+// when the response body is closed.
+func (t *limitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	release := func() {}
+	var waited time.Duration
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+		default:
+			start := time.Now()
+			select {
+			case t.slots <- struct{}{}:
+				waited = time.Since(start)
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		var once sync.Once
+		release = func() { once.Do(func() { <-t.slots }) }
+	}
+	if b := t.bucket(req); b != nil {
+		d, err := b.wait(ctx)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		waited += d
+	}
+	if waited > 0 && t.metrics != nil {
+		t.metrics.ObserveRateLimitWait(operationOf(ctx), waited)
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseBody calls release when it is closed.
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
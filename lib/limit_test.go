@@ -0,0 +1,85 @@
+package samplify_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+func TestRateLimits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":null}`))
+	}))
+	defer ts.Close()
+
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{
+		APIBaseURL:  ts.URL + "/api",
+		StatusURL:   ts.URL + "/status",
+		GatewayURL:  ts.URL + "/status/gateway",
+		InternalURL: ts.URL + "/internal",
+		RateLimits: &samplify.RateLimits{
+			API: &samplify.RateLimit{Rate: 20, Burst: 1},
+		},
+	})
+	client.Auth = getAuth()
+
+	start := time.Now()
+	hammer(t, 5, func() error {
+		_, err := client.GetProjectReport("p1")
+		return err
+	})
+	if elapsed := time.Since(start); elapsed < 190*time.Millisecond {
+		t.Errorf("expected 5 requests at 20/s to take 200ms, took %v", elapsed)
+	}
+
+	// The other hosts are not limited.
+	start = time.Now()
+	hammer(t, 5, func() error {
+		_, err := client.GetOrderDetails("o1")
+		return err
+	})
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected requests to the internal host not to be limited, took %v", elapsed)
+	}
+
+	// Waits are abandoned with their context.
+	client.GetProjectReport("p1")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetProjectReportWithContext(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the wait to end with the context, got %v", err)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	var inflight, max int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		for {
+			m := atomic.LoadInt32(&max)
+			if n <= m || atomic.CompareAndSwapInt32(&max, m, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inflight, -1)
+		w.Write([]byte(`{"data":null}`))
+	}))
+	defer ts.Close()
+
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL, MaxInFlight: 2})
+	client.Auth = getAuth()
+	hammer(t, 10, func() error {
+		_, err := client.GetFeasibility("p1", nil)
+		return err
+	})
+	if m := atomic.LoadInt32(&max); m != 2 {
+		t.Errorf("expected at most 2 concurrent requests, got %d", m)
+	}
+}
@@ -143,36 +143,25 @@ type LineItem struct {
 
 // IsUpdateable returns false if the line item cannot be updated.
 func (l *LineItem) IsUpdateable() bool {
-	return l.State == StateProvisioned ||
-		l.State == StateRejected
+	r, ok := lineItemStates[l.State]
+	return ok && r.updateable
 }
 
 // IsBuyable returns true if the lineitem can be bought or not
 func (l *LineItem) IsBuyable() bool {
-	return l.State == StateProvisioned ||
-		l.State == StateRejected ||
-		l.State == StateRejectedPaused
+	r, ok := lineItemStates[l.State]
+	return ok && r.buyable
 }
 
 // IsRebalanceable returns false if the line item cannot be updated.
 func (l *LineItem) IsRebalanceable() bool {
-	if l.State == StateClosed ||
-		l.State == StateCancelled ||
-		l.State == StateInvoiced ||
-		l.State == StateAwaitingClientApproval {
-		return false
-	}
-	return true
+	r, ok := lineItemStates[l.State]
+	return !ok || r.rebalanceable
 }
 
 // IsCloseable returns false if the line item cannot be updated.
 func (l *LineItem) IsCloseable() bool {
-	if l.State == StateClosed ||
-		l.State == StateCancelled ||
-		l.State == StateInvoiced {
-		return false
-	}
-	return true
+	return l.State.Allows(ActionClosed)
 }
 
 // CreateLineItemCriteria has the fields to create a LineItem
@@ -218,6 +207,10 @@ type UpdateLineItemCriteria struct {
 	Sources             *[]*LineItemSource `json:"sources,omitempty" valid:"optional"`
 	Targets             []*LineItemTarget  `json:"targets"`
 	SurveyTestingNotes  *string            `json:"surveyTestingNotes,omitempty" valid:"optional"`
+
+	// sent, if not nil, holds the JSON names of the only fields to send, see
+	// LineItemDiff.Criteria.
+	sent map[string]bool
 }
 
 // BuyProjectLineItem ...
@@ -0,0 +1,283 @@
+package samplify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Logger records what a Client does, as a message and alternating keys and
+// values. A *slog.Logger satisfies it, as do thin adapters around most
+// structured loggers.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// Redacted replaces secrets in logged headers and bodies.
+const Redacted = "REDACTED"
+
+// redactedFields are the JSON fields whose values are never logged: the
+// credentials, the tokens and the security keys of line items and sales
+// orders. They are matched case-insensitively.
+var redactedFields = map[string]bool{
+	"password":         true,
+	"clientsecret":     true,
+	"accesstoken":      true,
+	"refreshtoken":     true,
+	"securitykey1":     true,
+	"securitykey2":     true,
+	"basicsecuritykey": true,
+	"highsecuritykey":  true,
+}
+
+// redactedHeaders are the headers whose values are never logged.
+var redactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// maxLoggedBody is the size above which logged bodies are truncated.
+const maxLoggedBody = 64 << 10
+
+// logger returns the logger of o, or nil if logging is disabled.
+func (o *ClientOptions) logger() Logger {
+	if o == nil {
+		return nil
+	}
+	return o.Logger
+}
+
+type attemptKey struct{}
+
+// withAttempt returns a context recording that a request is the given attempt
+// at sending it, see retryTransport.
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// attemptOf returns the attempt recorded by withAttempt, or 1.
+func attemptOf(ctx context.Context) int {
+	if a, ok := ctx.Value(attemptKey{}).(int); ok {
+		return a
+	}
+	return 1
+}
+
+// logTransport logs every request sent with next.
+type logTransport struct {
+	next   http.RoundTripper
+	logger Logger
+	bodies bool
+}
+
+// newLogTransport wraps next with the logger of o, or returns next if logging
+// is disabled.
+func (o *ClientOptions) newLogTransport(next http.RoundTripper) http.RoundTripper {
+	if o.logger() == nil {
+		return next
+	}
+	return &logTransport{next: next, logger: o.Logger, bodies: o.LogBodies}
+}
+
+// RoundTrip sends req and logs its outcome.
+func (t *logTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	args := []interface{}{
+		"method", req.Method,
+		"path", req.URL.Path,
+		"attempt", attemptOf(req.Context()),
+	}
+	if t.bodies {
+		t.logger.Debug("samplify request", append(args[:len(args):len(args)],
+			"headers", redactHeader(req.Header),
+			"body", requestBody(req))...)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	args = append(args, "duration", time.Since(start))
+	if err != nil {
+		t.logger.Error("samplify request failed", append(args, "error", err)...)
+		return resp, err
+	}
+
+	args = append(args, "status", resp.StatusCode, "requestId", resp.Header.Get("x-request-id"))
+	if t.bodies {
+		body, rerr := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if rerr != nil {
+			return nil, rerr
+		}
+		t.logger.Debug("samplify response", append(args[:len(args):len(args)],
+			"headers", redactHeader(resp.Header),
+			"body", redactBody(resp.Header.Get("Content-Type"), body))...)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		t.logger.Warn("samplify request failed", args...)
+	} else {
+		t.logger.Info("samplify request", args...)
+	}
+	return resp, nil
+}
+
+// requestBody returns the redacted body of req for logging.
+func requestBody(req *http.Request) string {
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	return redactBody(req.Header.Get("Content-Type"), b)
+}
+
+// redactHeader returns a copy of h without the values of redactedHeaders.
+func redactHeader(h http.Header) http.Header {
+	r := h.Clone()
+	for _, k := range redactedHeaders {
+		v := r[http.CanonicalHeaderKey(k)]
+		for i := range v {
+			if strings.HasPrefix(v[i], "Bearer ") {
+				v[i] = "Bearer " + Redacted
+			} else {
+				v[i] = Redacted
+			}
+		}
+	}
+	return r
+}
+
+// redactBody returns body for logging, with the values of redactedFields
+// replaced if it is JSON. Other bodies, such as uploaded files, are only
+// described by their size.
+func redactBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v interface{}
+	if !strings.Contains(contentType, "json") || json.Unmarshal(body, &v) != nil {
+		return fmt.Sprintf("<%d bytes of %s>", len(body), contentType)
+	}
+	b, err := json.Marshal(redact(v))
+	if err != nil {
+		return ""
+	}
+	if len(b) > maxLoggedBody {
+		return string(b[:maxLoggedBody]) + "..."
+	}
+	return string(b)
+}
+
+// redact replaces the values of redactedFields in v, a decoded JSON value.
+func redact(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			if redactedFields[strings.ToLower(k)] {
+				v[k] = Redacted
+			} else {
+				v[k] = redact(e)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = redact(e)
+		}
+	}
+	return v
+}
+
+// TextLogger is a Logger that writes one line per message, with the keys and
+// values formatted as key=value. Messages below Level are discarded. It is
+// meant for tools and debugging; services should use their own structured
+// logger.
+type TextLogger struct {
+	Level LogLevel
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// LogLevel is the severity of a logged message.
+type LogLevel int
+
+// LogLevel values, in increasing order of severity
+const (
+	LevelDebug LogLevel = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the name of l, e.g. "INFO".
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// NewTextLogger returns a TextLogger that writes the messages of the given
+// level and above to w.
+func NewTextLogger(w io.Writer, level LogLevel) *TextLogger {
+	return &TextLogger{Level: level, w: w}
+}
+
+// Debug logs msg at LevelDebug.
+func (l *TextLogger) Debug(msg string, args ...interface{}) { l.log(LevelDebug, msg, args) }
+
+// Info logs msg at LevelInfo.
+func (l *TextLogger) Info(msg string, args ...interface{}) { l.log(LevelInfo, msg, args) }
+
+// Warn logs msg at LevelWarn.
+func (l *TextLogger) Warn(msg string, args ...interface{}) { l.log(LevelWarn, msg, args) }
+
+// Error logs msg at LevelError.
+func (l *TextLogger) Error(msg string, args ...interface{}) { l.log(LevelError, msg, args) }
+
+func (l *TextLogger) log(level LogLevel, msg string, args []interface{}) {
+	if level < l.Level {
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s %q", time.Now().Format(time.RFC3339), level, msg)
+	for i := 0; i < len(args); i += 2 {
+		key, val := "!BADKEY", args[i]
+		if i+1 < len(args) {
+			key, val = fmt.Sprint(args[i]), args[i+1]
+		}
+		s := fmt.Sprint(val)
+		if h, ok := val.(http.Header); ok {
+			j, _ := json.Marshal(h)
+			s = string(j)
+		}
+		if strings.ContainsAny(s, " \t\n\"=") || len(s) == 0 {
+			s = fmt.Sprintf("%q", s)
+		}
+		fmt.Fprintf(&b, " %s=%s", key, s)
+	}
+	b.WriteByte('\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, b.String())
+}
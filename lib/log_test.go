@@ -0,0 +1,103 @@
+package samplify_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+	"github.com/researchnow/go-samplifyapi-client/lib/samplifytest"
+)
+
+// testLogger records logged messages as "LEVEL msg key=value ...".
+type testLogger struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *testLogger) log(level, msg string, args []interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", level, msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	l.mu.Lock()
+	l.lines = append(l.lines, b.String())
+	l.mu.Unlock()
+}
+
+func (l *testLogger) Debug(msg string, args ...interface{}) { l.log("DEBUG", msg, args) }
+func (l *testLogger) Info(msg string, args ...interface{})  { l.log("INFO", msg, args) }
+func (l *testLogger) Warn(msg string, args ...interface{})  { l.log("WARN", msg, args) }
+func (l *testLogger) Error(msg string, args ...interface{}) { l.log("ERROR", msg, args) }
+
+func (l *testLogger) find(substr ...string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+next:
+	for _, line := range l.lines {
+		for _, s := range substr {
+			if !strings.Contains(line, s) {
+				continue next
+			}
+		}
+		return line
+	}
+	return ""
+}
+
+func TestLogger(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	s.SetCredentials(samplifytest.ClientID, samplifytest.Username, "s3cret")
+	s.InjectError(http.MethodGet, "/projects", http.StatusServiceUnavailable, 1)
+
+	logger := &testLogger{}
+	client := s.NewClient()
+	client.Credentials.Password = "s3cret"
+	client.Options.Logger = logger
+	client.Options.LogBodies = true
+	client.Options.Retry = &samplify.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	if _, err := client.GetAllProjects(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range [][]string{
+		{"INFO samplify request ", "method=POST", "path=/auth/v1/token/password", "status=200"},
+		{"INFO samplify tokens renewed", "mode=login"},
+		{"WARN samplify request failed", "method=GET", "path=/sample/v1/projects", "attempt=1", "status=503"},
+		{"WARN samplify retrying request", "attempt=1", "status=503"},
+		{"INFO samplify request ", "path=/sample/v1/projects", "attempt=2", "status=200", "duration="},
+		{"DEBUG samplify request ", "path=/auth/v1/token/password", `"password":"REDACTED"`},
+		{"DEBUG samplify request ", "path=/sample/v1/projects", "Bearer REDACTED"},
+		{"DEBUG samplify response ", `"accessToken":"REDACTED"`, `"refreshToken":"REDACTED"`},
+	} {
+		if logger.find(want...) == "" {
+			t.Errorf("no log line with %q in:\n%s", want, strings.Join(logger.lines, "\n"))
+		}
+	}
+	for _, secret := range []string{"s3cret", "access-1", "refresh-1"} {
+		if line := logger.find(secret); line != "" {
+			t.Errorf("secret %q logged in %q", secret, line)
+		}
+	}
+}
+
+func TestTextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := samplify.NewTextLogger(&buf, samplify.LevelInfo)
+	l.Debug("hidden")
+	l.Info("samplify request", "method", "GET", "path", "/projects", "requestId", "")
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Errorf("debug message logged at info level: %q", out)
+	}
+	if !strings.Contains(out, ` INFO "samplify request" method=GET path=/projects requestId=""`) {
+		t.Errorf("unexpected output %q", out)
+	}
+}
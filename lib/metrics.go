@@ -0,0 +1,267 @@
+package samplify
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics records measurements of the requests a Client sends. Operations are
+// named after the Client method that sends the request, such as
+// "GetProjectReport", or after the token request, such as "Login", so that
+// they do not depend on the IDs in the URL. PrometheusMetrics implements it.
+type Metrics interface {
+	// ObserveRequest is called after every HTTP request, including each
+	// attempt of a retried request. status is 0 if no response was received.
+	ObserveRequest(operation string, status int, duration time.Duration)
+	// ObserveRetry is called before a failed request is retried.
+	ObserveRetry(operation string)
+	// ObserveTokenRenewal is called after the client renewed its tokens, with
+	// mode "login", "refresh" or "stored".
+	ObserveTokenRenewal(mode string, err error)
+	// ObserveRateLimitWait is called after a request waited for the rate
+	// limits or MaxInFlight of the client before it was sent.
+	ObserveRateLimitWait(operation string, wait time.Duration)
+}
+
+// StatusClass returns the class of an HTTP status for use as a label, e.g.
+// "2xx" or "5xx", or "error" if status is 0 because no response was received.
+func StatusClass(status int) string {
+	if status < 100 || status > 599 {
+		return "error"
+	}
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+// metricsTransport records the requests sent with next.
+type metricsTransport struct {
+	next    http.RoundTripper
+	metrics Metrics
+}
+
+// newMetricsTransport wraps next with the metrics of o, or returns next if
+// there are none.
+func (o *ClientOptions) newMetricsTransport(next http.RoundTripper) http.RoundTripper {
+	if o.Metrics == nil {
+		return next
+	}
+	return &metricsTransport{next: next, metrics: o.Metrics}
+}
+
+// RoundTrip sends req and records its status and duration.
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	status := 0
+	if err == nil {
+		status = resp.StatusCode
+	}
+	t.metrics.ObserveRequest(operationOf(req.Context()), status, time.Since(start))
+	return resp, err
+}
+
+// DefaultBuckets are the upper bounds in seconds of the buckets of the
+// histograms of PrometheusMetrics, the same as those of the Prometheus client
+// libraries.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// PrometheusMetrics is a Metrics that keeps the measurements in memory and
+// serves them over HTTP in the Prometheus text exposition format:
+//
+//	samplify_requests_total{operation,status_class}            counter
+//	samplify_request_duration_seconds{operation}               histogram
+//	samplify_retries_total{operation}                          counter
+//	samplify_token_renewals_total{mode,result}                 counter
+//	samplify_rate_limit_wait_seconds{operation}                histogram
+//
+// Register it with a mux, e.g. at /metrics, or write the metrics elsewhere
+// with WriteTo. It is safe for concurrent use and can be shared by
+// several clients.
+type PrometheusMetrics struct {
+	buckets []float64
+
+	mu        sync.Mutex
+	requests  map[[2]string]uint64
+	durations map[string]*histogram
+	retries   map[string]uint64
+	renewals  map[[2]string]uint64
+	rateWaits map[string]*histogram
+}
+
+// histogram counts observations in cumulative buckets.
+type histogram struct {
+	counts []uint64 // one per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// NewPrometheusMetrics returns an empty PrometheusMetrics. The histograms use
+// buckets, or DefaultBuckets if none are given.
+func NewPrometheusMetrics(buckets ...float64) *PrometheusMetrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	// The +Inf bucket is always written.
+	var b []float64
+	for _, le := range buckets {
+		if !math.IsInf(le, 1) {
+			b = append(b, le)
+		}
+	}
+	sort.Float64s(b)
+	return &PrometheusMetrics{
+		buckets:   b,
+		requests:  map[[2]string]uint64{},
+		durations: map[string]*histogram{},
+		retries:   map[string]uint64{},
+		renewals:  map[[2]string]uint64{},
+		rateWaits: map[string]*histogram{},
+	}
+}
+
+// ObserveRequest implements Metrics.
+func (m *PrometheusMetrics) ObserveRequest(operation string, status int, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[[2]string{operation, StatusClass(status)}]++
+	m.observe(m.durations, operation, duration)
+}
+
+// ObserveRetry implements Metrics.
+func (m *PrometheusMetrics) ObserveRetry(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[operation]++
+}
+
+// ObserveTokenRenewal implements Metrics.
+func (m *PrometheusMetrics) ObserveTokenRenewal(mode string, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.renewals[[2]string{mode, result}]++
+}
+
+// ObserveRateLimitWait implements Metrics.
+func (m *PrometheusMetrics) ObserveRateLimitWait(operation string, wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.observe(m.rateWaits, operation, wait)
+}
+
+// observe adds d to the histogram of operation in hs. m.mu must be held.
+func (m *PrometheusMetrics) observe(hs map[string]*histogram, operation string, d time.Duration) {
+	h := hs[operation]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(m.buckets))}
+		hs[operation] = h
+	}
+	v := d.Seconds()
+	h.count++
+	h.sum += v
+	if i := sort.SearchFloat64s(m.buckets, v); i < len(m.buckets) {
+		h.counts[i]++
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics to w in the Prometheus text exposition format.
+func (m *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	m.mu.Lock()
+	writeHeader(&b, "samplify_requests_total", "counter", "HTTP requests sent, including retries.")
+	for _, k := range sortedPairs(m.requests) {
+		fmt.Fprintf(&b, "samplify_requests_total{operation=%s,status_class=%s} %d\n",
+			quoteLabel(k[0]), quoteLabel(k[1]), m.requests[k])
+	}
+	m.writeHistograms(&b, "samplify_request_duration_seconds", "Duration of HTTP requests.", m.durations)
+	writeHeader(&b, "samplify_retries_total", "counter", "Retries of failed requests.")
+	for _, op := range sortedKeys(m.retries) {
+		fmt.Fprintf(&b, "samplify_retries_total{operation=%s} %d\n", quoteLabel(op), m.retries[op])
+	}
+	writeHeader(&b, "samplify_token_renewals_total", "counter",
+		"Token renewals by mode (login, refresh, or stored when taken from the TokenSource) and result (success, failure).")
+	for _, k := range sortedPairs(m.renewals) {
+		fmt.Fprintf(&b, "samplify_token_renewals_total{mode=%s,result=%s} %d\n",
+			quoteLabel(k[0]), quoteLabel(k[1]), m.renewals[k])
+	}
+	m.writeHistograms(&b, "samplify_rate_limit_wait_seconds", "Time requests waited for the client-side rate limits.", m.rateWaits)
+	m.mu.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// writeHistograms writes the histograms hs of the metric name. m.mu must be
+// held.
+func (m *PrometheusMetrics) writeHistograms(b *strings.Builder, name, help string, hs map[string]*histogram) {
+	writeHeader(b, name, "histogram", help)
+	ops := make([]string, 0, len(hs))
+	for op := range hs {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	for _, op := range ops {
+		h := hs[op]
+		label := quoteLabel(op)
+		var cumulative uint64
+		for i, le := range m.buckets {
+			cumulative += h.counts[i]
+			fmt.Fprintf(b, "%s_bucket{operation=%s,le=\"%s\"} %d\n", name, label, formatFloat(le), cumulative)
+		}
+		fmt.Fprintf(b, "%s_bucket{operation=%s,le=\"+Inf\"} %d\n", name, label, h.count)
+		fmt.Fprintf(b, "%s_sum{operation=%s} %s\n", name, label, formatFloat(h.sum))
+		fmt.Fprintf(b, "%s_count{operation=%s} %d\n", name, label, h.count)
+	}
+}
+
+func writeHeader(b *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// quoteLabel quotes a label value, escaping backslashes, quotes and newlines.
+func quoteLabel(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedPairs(m map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+	return keys
+}
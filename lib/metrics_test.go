@@ -0,0 +1,68 @@
+package samplify_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+	"github.com/researchnow/go-samplifyapi-client/lib/samplifytest"
+)
+
+func TestPrometheusMetrics(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	s.InjectError(http.MethodGet, "/projects/*/report", http.StatusBadGateway, 1)
+
+	metrics := samplify.NewPrometheusMetrics()
+	client := s.NewClient()
+	client.Options.Metrics = metrics
+	client.Options.Retry = &samplify.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+	client.Options.RateLimits = &samplify.RateLimits{API: &samplify.RateLimit{Rate: 20, Burst: 1}}
+
+	if _, err := client.CreateProject(getProjectCriteria()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetProjectReport("project001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetProjectBy("nope"); err == nil {
+		t.Fatal("expected an error for an unknown project")
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE samplify_requests_total counter\n",
+		`samplify_requests_total{operation="Login",status_class="2xx"} 1`,
+		`samplify_requests_total{operation="CreateProject",status_class="2xx"} 1`,
+		`samplify_requests_total{operation="GetProjectReport",status_class="2xx"} 1`,
+		`samplify_requests_total{operation="GetProjectReport",status_class="5xx"} 1`,
+		`samplify_requests_total{operation="GetProjectBy",status_class="4xx"} 1`,
+		"# TYPE samplify_request_duration_seconds histogram\n",
+		`samplify_request_duration_seconds_bucket{operation="GetProjectReport",le="+Inf"} 2`,
+		`samplify_request_duration_seconds_count{operation="GetProjectReport"} 2`,
+		`samplify_retries_total{operation="GetProjectReport"} 1`,
+		"# HELP samplify_token_renewals_total Token renewals by mode (login, refresh, or stored when taken from the TokenSource) and result (success, failure).\n",
+		`samplify_token_renewals_total{mode="login",result="success"} 1`,
+		`samplify_rate_limit_wait_seconds_count{operation="GetProjectReport"} `,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "project001") {
+		t.Errorf("project ID used as a label:\n%s", out)
+	}
+}
+
+func TestStatusClass(t *testing.T) {
+	for status, want := range map[int]string{0: "error", 200: "2xx", 204: "2xx", 404: "4xx", 503: "5xx"} {
+		if got := samplify.StatusClass(status); got != want {
+			t.Errorf("StatusClass(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
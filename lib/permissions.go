@@ -6,6 +6,15 @@ type ProjectPermissionsResponse struct {
 	ResponseStatus     ResponseStatus      `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *ProjectPermissionsResponse) Envelope() *Envelope[*ProjectPermissions] {
+	return &Envelope[*ProjectPermissions]{Data: r.ProjectPermissions, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *ProjectPermissionsResponse) setEnvelope(e *Envelope[*ProjectPermissions]) {
+	r.ProjectPermissions, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // ProjectPermissions ...
 type ProjectPermissions struct {
 	ExtProjectID string      `json:"extProjectId"`
@@ -0,0 +1,181 @@
+package samplify
+
+import (
+	"math"
+	"time"
+)
+
+// ProjectionOptions configure ProjectQuotaCells.
+type ProjectionOptions struct {
+	// Now is the time of the report. Defaults to the current time.
+	Now time.Time
+	// Deadline is the time by which the cells should be filled. Defaults to
+	// the end time of the field schedule of the line item or, if it has
+	// none, to its launch time plus its days in field.
+	Deadline time.Time
+}
+
+// LineItemProgress is the completion of the quota cells of a line item, see
+// ProjectQuotaCells.
+type LineItemProgress struct {
+	ExtLineItemID string
+	// Deadline is the time by which the cells should be filled, or zero if
+	// it is unknown.
+	Deadline time.Time
+	Cells    []*QuotaCellProgress
+}
+
+// AtRisk returns the cells at risk of not filling by the deadline.
+func (p *LineItemProgress) AtRisk() []*QuotaCellProgress {
+	var cells []*QuotaCellProgress
+	for _, c := range p.Cells {
+		if c.AtRisk {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+// QuotaCellProgress is the completion of a quota cell.
+type QuotaCellProgress struct {
+	QuotaGroupID string
+	QuotaCellID  string
+	QuotaNodes   []*QuotaNode
+	Status       *QCellStatusType
+	// Target is the count of the cell, or its percentage of the required
+	// completes of the line item.
+	Target    int64
+	Completes int64
+	// Remaining is the number of completes needed to reach Target.
+	Remaining int64
+	// FillRate is Completes divided by Target, 1 for a cell without target.
+	FillRate float64
+	// Velocity is the number of completes per hour since the launch of the
+	// line item.
+	Velocity float64
+	// ProjectedFinish is when the cell will be filled at Velocity, the time
+	// of the report if it is filled, or zero if it has no completes yet.
+	ProjectedFinish time.Time
+	// AtRisk reports whether the cell is not filled and, at Velocity, will
+	// not be by the deadline. Paused cells are not at risk, as they are not
+	// meant to fill.
+	AtRisk bool
+}
+
+// ProjectQuotaCells joins the quota plan of item with the quota cell stats of
+// its detailed report, and projects when each cell will be filled from the
+// completes since the launch of the line item. Cells are matched by
+// QuotaCellID or, if the report has no ID, by quota nodes. Cells are not
+// projected before the line item is launched.
+func ProjectQuotaCells(item *LineItem, report *DetailedLineItemReport, opts *ProjectionOptions) (*LineItemProgress, error) {
+	if item == nil || report == nil {
+		return nil, ErrRequiredFieldEmpty
+	}
+	if opts == nil {
+		opts = &ProjectionOptions{}
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	p := &LineItemProgress{ExtLineItemID: item.ExtLineItemID, Deadline: opts.Deadline}
+	if p.Deadline.IsZero() {
+		p.Deadline = deadlineOf(item)
+	}
+	var elapsed time.Duration
+	if item.LaunchedAt != nil && item.LaunchedAt.IsSet() {
+		elapsed = now.Sub(item.LaunchedAt.Time)
+	}
+
+	stats := map[string]*DetailedQuotaCellReport{}
+	byNodes := map[string]*DetailedQuotaCellReport{}
+	for _, g := range report.QuotaGroups {
+		if g == nil {
+			continue
+		}
+		for _, c := range g.QuotaCells {
+			if c == nil {
+				continue
+			}
+			if c.QuotaCellID != "" {
+				stats[c.QuotaCellID] = c
+			}
+			byNodes[quotaNodesKey(&QuotaCell{QuotaNodes: c.QuotaNodes})] = c
+		}
+	}
+
+	if item.QuotaPlan == nil {
+		return p, nil
+	}
+	for _, g := range nonNil(item.QuotaPlan.QuotaGroups) {
+		for _, cell := range nonNil(g.QuotaCells) {
+			c := &QuotaCellProgress{
+				QuotaGroupID: quotaGroupID(g),
+				QuotaCellID:  quotaCellID(cell),
+				QuotaNodes:   cell.QuotaNodes,
+				Status:       cell.Status,
+				Target:       cellTarget(cell, item.RequiredCompletes),
+			}
+			r, ok := stats[c.QuotaCellID]
+			if !ok {
+				r = byNodes[quotaNodesKey(cell)]
+			}
+			if r != nil {
+				c.Completes = r.Stats.Completes
+			}
+			c.project(now, elapsed, p.Deadline)
+			p.Cells = append(p.Cells, c)
+		}
+	}
+	return p, nil
+}
+
+// project computes the progress of c from its completes, elapsed since the
+// launch.
+func (c *QuotaCellProgress) project(now time.Time, elapsed time.Duration, deadline time.Time) {
+	c.FillRate = 1
+	if c.Target > 0 {
+		c.FillRate = float64(c.Completes) / float64(c.Target)
+	}
+	if c.Remaining = c.Target - c.Completes; c.Remaining <= 0 {
+		c.Remaining = 0
+		c.ProjectedFinish = now
+		return
+	}
+	if elapsed <= 0 {
+		return
+	}
+	c.Velocity = float64(c.Completes) / elapsed.Hours()
+	if c.Velocity > 0 {
+		c.ProjectedFinish = now.Add(time.Duration(float64(c.Remaining) / c.Velocity * float64(time.Hour)))
+	}
+	paused := c.Status != nil && *c.Status == QCellStatusTypePause
+	c.AtRisk = !paused && !deadline.IsZero() && (c.ProjectedFinish.IsZero() || c.ProjectedFinish.After(deadline))
+}
+
+// cellTarget returns the number of completes of cell.
+func cellTarget(cell *QuotaCell, requiredCompletes int64) int64 {
+	switch {
+	case cell.Count != nil:
+		return int64(*cell.Count)
+	case cell.Perc != nil:
+		return int64(math.Round(*cell.Perc * float64(requiredCompletes) / 100))
+	}
+	return 0
+}
+
+// deadlineOf returns the end of the field period of item, or zero if it is
+// unknown.
+func deadlineOf(item *LineItem) time.Time {
+	if item.FieldSchedule != nil && item.FieldSchedule.EndTime != "" {
+		for _, layout := range []string{ctLayout, time.RFC3339} {
+			if t, err := time.Parse(layout, item.FieldSchedule.EndTime); err == nil {
+				return t
+			}
+		}
+	}
+	if item.LaunchedAt != nil && item.LaunchedAt.IsSet() && item.DaysInField > 0 {
+		return item.LaunchedAt.AddDate(0, 0, int(item.DaysInField))
+	}
+	return time.Time{}
+}
@@ -0,0 +1,73 @@
+package samplify_test
+
+import (
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+func TestProjectQuotaCells(t *testing.T) {
+	launched := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	item := &samplify.LineItem{
+		DaysInField:       5,
+		RequiredCompletes: 200,
+		QuotaPlan:         remotePlan(),
+	}
+	item.ExtLineItemID = "lineItem001"
+	item.LaunchedAt = &samplify.CustomTime{Time: launched}
+	report := &samplify.DetailedLineItemReport{
+		QuotaGroups: []*samplify.DetailedQuotaGroupReport{{
+			QuotaGroupID: "g1",
+			QuotaCells: []*samplify.DetailedQuotaCellReport{
+				// Matched by ID.
+				{QuotaCellID: "c1", Stats: samplify.DetailedStats{Completes: 80}},
+				// Matched by quota nodes.
+				{QuotaNodes: []*samplify.QuotaNode{{AttributeID: "11", Options: []string{"2"}}}, Stats: samplify.DetailedStats{Completes: 24}},
+			},
+		}},
+	}
+
+	now := launched.Add(48 * time.Hour)
+	p, err := samplify.ProjectQuotaCells(item, report, &samplify.ProjectionOptions{Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Deadline.Equal(launched.AddDate(0, 0, 5)) || len(p.Cells) != 2 {
+		t.Fatalf("unexpected progress %+v", p)
+	}
+
+	male, female := p.Cells[0], p.Cells[1]
+	if male.QuotaCellID != "c1" || male.Target != 80 || male.Completes != 80 || male.Remaining != 0 ||
+		male.FillRate != 1 || !male.ProjectedFinish.Equal(now) || male.AtRisk {
+		t.Errorf("unexpected progress of the filled cell %+v", male)
+	}
+	// 24 completes in 48 hours, 96 remaining: 192 more hours, after the
+	// deadline in 72 hours.
+	if female.Target != 120 || female.Completes != 24 || female.Remaining != 96 || female.FillRate != 0.2 ||
+		female.Velocity != 0.5 || !female.ProjectedFinish.Equal(now.Add(192*time.Hour)) || !female.AtRisk {
+		t.Errorf("unexpected progress of the slow cell %+v", female)
+	}
+	if r := p.AtRisk(); len(r) != 1 || r[0] != female {
+		t.Errorf("expected the slow cell at risk, got %+v", r)
+	}
+
+	// A paused cell is not at risk.
+	paused := samplify.QCellStatusTypePause
+	item.QuotaPlan.QuotaGroups[0].QuotaCells[1].Status = &paused
+	if p, err = samplify.ProjectQuotaCells(item, report, &samplify.ProjectionOptions{Now: now}); err != nil {
+		t.Fatal(err)
+	}
+	if r := p.AtRisk(); len(r) != 0 || p.Cells[1].Remaining != 96 {
+		t.Errorf("expected the paused cell not to be at risk, got %+v", r)
+	}
+	item.QuotaPlan.QuotaGroups[0].QuotaCells[1].Status = nil
+
+	p, err = samplify.ProjectQuotaCells(item, report, &samplify.ProjectionOptions{Now: now, Deadline: now.Add(200 * time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.AtRisk()) != 0 {
+		t.Errorf("expected no cells at risk with a later deadline, got %+v", p.AtRisk())
+	}
+}
@@ -3,6 +3,7 @@ package samplify
 import (
 	"fmt"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -59,7 +60,8 @@ type Filter struct {
 	Value Value
 }
 
-// Value ...
+// Value of a filter. String returns the unescaped value, which is encoded
+// when the query is built.
 type Value interface {
 	String() string
 }
@@ -76,7 +78,7 @@ type FilterValue struct {
 }
 
 func (filtervalue FilterValue) String() string {
-	return url.QueryEscape(fmt.Sprintf("%s", filtervalue.Value))
+	return fmt.Sprintf("%v", filtervalue.Value)
 }
 
 func (datefilter DateFilterValue) String() string {
@@ -111,6 +113,44 @@ func (is IntSlice) String() string {
 	return strings.Join(strvals, ",")
 }
 
+// Eq returns a filter value that matches v, such as a string or one of the
+// State values.
+func Eq(v interface{}) FilterValue {
+	return FilterValue{Value: v}
+}
+
+// DateRange returns a filter value that matches the dates between from and
+// to. A zero time leaves its end of the range open.
+func DateRange(from, to time.Time) DateFilterValue {
+	v := DateFilterValue{}
+	if !from.IsZero() {
+		v.From = &from
+	}
+	if !to.IsZero() {
+		v.To = &to
+	}
+	return v
+}
+
+// Strings returns a filter value that matches any of values.
+func Strings(values ...string) StringSlice {
+	return StringSlice(values)
+}
+
+// Ints returns a filter value that matches any of values.
+func Ints(values ...int) IntSlice {
+	return IntSlice(values)
+}
+
+// States returns a filter value that matches any of states.
+func States(states ...State) StringSlice {
+	ss := make(StringSlice, len(states))
+	for i, s := range states {
+		ss[i] = string(s)
+	}
+	return ss
+}
+
 const maxLimit uint = 1000
 
 // QueryOptions ... Filtering/Sorting and pagination params for GET endpoints that return an object list
@@ -126,52 +166,183 @@ type QueryOptions struct {
 	EventType     *string `conform:"trim"`
 }
 
-func query2String(options *QueryOptions) string {
-	query := ""
-	if options != nil {
-		query = "?"
-		sep := ""
-		if len(options.Scope) > 0 {
-			query = fmt.Sprintf("?scope=%s", url.QueryEscape(options.Scope))
-			sep = "&amp;"
-		}
-		if len(options.FilterBy) > 0 {
-			for _, f := range options.FilterBy {
-				query = fmt.Sprintf("%s%s%s=%s", query, sep, f.Field, f.Value.String())
-				sep = "&amp;"
-			}
+// NewQuery returns empty QueryOptions, to be built with the chainable methods
+// of QueryOptions:
+//
+//	q := NewQuery().Filter(QueryFieldState, Eq(StateLaunched)).SortDesc(QueryFieldCreatedAt).Page(2, 100)
+func NewQuery() *QueryOptions {
+	return &QueryOptions{}
+}
+
+// Filter adds a filter on field.
+func (o *QueryOptions) Filter(field QueryField, value Value) *QueryOptions {
+	o.FilterBy = append(o.FilterBy, &Filter{Field: field, Value: value})
+	return o
+}
+
+// SortAsc sorts by field in ascending order, after the previous sorts.
+func (o *QueryOptions) SortAsc(field QueryField) *QueryOptions {
+	o.SortBy = append(o.SortBy, &Sort{Field: field, Direction: SortDirectionAsc})
+	return o
+}
+
+// SortDesc sorts by field in descending order, after the previous sorts.
+func (o *QueryOptions) SortDesc(field QueryField) *QueryOptions {
+	o.SortBy = append(o.SortBy, &Sort{Field: field, Direction: SortDirectionDesc})
+	return o
+}
+
+// Page selects the given page, starting at 1, of size items.
+func (o *QueryOptions) Page(page, size uint) *QueryOptions {
+	if page < 1 {
+		page = 1
+	}
+	o.Offset = (page - 1) * size
+	o.Limit = size
+	return o
+}
+
+// WithScope sets the scope of the query.
+func (o *QueryOptions) WithScope(scope string) *QueryOptions {
+	o.Scope = scope
+	return o
+}
+
+// WithExtProjectID restricts the query to a project.
+func (o *QueryOptions) WithExtProjectID(extProjectID string) *QueryOptions {
+	o.ExtProjectId = &extProjectID
+	return o
+}
+
+// WithExtLineItemID restricts the query to a line item.
+func (o *QueryOptions) WithExtLineItemID(extLineItemID string) *QueryOptions {
+	o.ExtLineItemId = &extLineItemID
+	return o
+}
+
+// WithEventType restricts the query to an event type.
+func (o *QueryOptions) WithEventType(eventType string) *QueryOptions {
+	o.EventType = &eventType
+	return o
+}
+
+// Values returns the query parameters of o. Limit is capped at 1000.
+func (o *QueryOptions) Values() url.Values {
+	v := url.Values{}
+	if o == nil {
+		return v
+	}
+	if len(o.Scope) > 0 {
+		v.Set("scope", o.Scope)
+	}
+	for _, f := range o.FilterBy {
+		if f != nil && f.Value != nil {
+			v.Add(string(f.Field), f.Value.String())
 		}
-		if len(options.SortBy) > 0 {
-			query = fmt.Sprintf("%s%ssort=", query, sep)
-			sep = ""
-			for _, s := range options.SortBy {
-				query = fmt.Sprintf("%s%s%s:%s", query, sep, s.Field, s.Direction)
-				sep = ","
+	}
+	if len(o.SortBy) > 0 {
+		sorts := make([]string, 0, len(o.SortBy))
+		for _, s := range o.SortBy {
+			if s != nil {
+				sorts = append(sorts, fmt.Sprintf("%s:%s", s.Field, s.Direction))
 			}
 		}
-		if len(sep) > 0 {
-			sep = "&amp;"
-		}
-		if options.Offset > 0 {
-			query = fmt.Sprintf("%s%soffset=%d", query, sep, options.Offset)
-			sep = "&amp;"
+		v.Set("sort", strings.Join(sorts, ","))
+	}
+	if o.Offset > 0 {
+		v.Set("offset", strconv.FormatUint(uint64(o.Offset), 10))
+	}
+	if o.Limit > 0 {
+		limit := o.Limit
+		if limit > maxLimit {
+			limit = maxLimit
 		}
-		if options.Limit > 0 {
-			if options.Limit > maxLimit {
-				options.Limit = maxLimit
+		v.Set("limit", strconv.FormatUint(uint64(limit), 10))
+	}
+	if o.ExtProjectId != nil {
+		v.Add("extProjectId", *o.ExtProjectId)
+	}
+	if o.ExtLineItemId != nil {
+		v.Add("extLineItemId", *o.ExtLineItemId)
+	}
+	if o.EventType != nil {
+		v.Set("eventType", *o.EventType)
+	}
+	return v
+}
+
+// Encode returns the URL-encoded query string of o, without a leading "?".
+func (o *QueryOptions) Encode() string {
+	return o.Values().Encode()
+}
+
+// ParseQuery parses a query string, such as the one returned by Encode, into
+// QueryOptions. Parameters other than the known pagination, sorting and scope
+// parameters are returned as filters with a FilterValue, in the order of
+// their names.
+func ParseQuery(query string) (*QueryOptions, error) {
+	values, err := url.ParseQuery(strings.TrimPrefix(query, "?"))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	o := &QueryOptions{}
+	for _, k := range keys {
+		for _, v := range values[k] {
+			switch k {
+			case "scope":
+				o.Scope = v
+			case "sort":
+				for _, s := range strings.Split(v, ",") {
+					parts := strings.Split(s, ":")
+					if len(parts) != 2 {
+						return nil, fmt.Errorf("invalid sort %q: %w", s, ErrInvalidFieldValue)
+					}
+					o.SortBy = append(o.SortBy, &Sort{Field: QueryField(parts[0]), Direction: SortDirection(parts[1])})
+				}
+			case "offset", "limit":
+				n, err := strconv.ParseUint(v, 10, 0)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s %q: %w", k, v, ErrInvalidFieldValue)
+				}
+				if k == "offset" {
+					o.Offset = uint(n)
+				} else {
+					o.Limit = uint(n)
+				}
+			case "extProjectId":
+				if o.ExtProjectId == nil {
+					o.WithExtProjectID(v)
+				} else {
+					o.Filter(QueryField(k), Eq(v))
+				}
+			case "extLineItemId":
+				if o.ExtLineItemId == nil {
+					o.WithExtLineItemID(v)
+				} else {
+					o.Filter(QueryField(k), Eq(v))
+				}
+			case "eventType":
+				o.WithEventType(v)
+			default:
+				o.Filter(QueryField(k), Eq(v))
 			}
-			query = fmt.Sprintf("%s%slimit=%d", query, sep, options.Limit)
-		}
-		if options.ExtProjectId != nil {
-			query = fmt.Sprintf("%s%sextProjectId=%s", query, sep, url.QueryEscape(*options.ExtProjectId))
-			sep = "&amp;"
-		}
-		if options.ExtLineItemId != nil {
-			query = fmt.Sprintf("%s%sextLineItemId=%s", query, sep, url.QueryEscape(*options.ExtLineItemId))
-		}
-		if options.EventType != nil {
-			query = fmt.Sprintf("%s%seventType=%s", query, sep, url.QueryEscape(*options.EventType))
 		}
 	}
-	return query
+	return o, nil
+}
+
+// query2String returns the query string of options, including the leading
+// "?", or an empty string if there are no parameters.
+func query2String(options *QueryOptions) string {
+	query := options.Encode()
+	if len(query) == 0 {
+		return ""
+	}
+	return "?" + query
 }
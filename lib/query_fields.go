@@ -0,0 +1,159 @@
+package samplify
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupportedQueryField ... Matched by a *QueryFieldError with errors.Is
+var ErrUnsupportedQueryField = errors.New("query field is not supported by the endpoint")
+
+// Endpoint identifies a list endpoint of the API, for the validation of
+// QueryOptions.
+type Endpoint string
+
+// Endpoint values, named after the Client methods that request them
+const (
+	EndpointProjects        Endpoint = "GetAllProjects"
+	EndpointLineItems       Endpoint = "GetAllLineItems"
+	EndpointFeasibility     Endpoint = "GetFeasibility"
+	EndpointEvents          Endpoint = "GetEvents"
+	EndpointInvoicesSummary Endpoint = "GetInvoicesSummary"
+	EndpointTemplates       Endpoint = "GetTemplateList"
+	EndpointCountries       Endpoint = "GetCountries"
+	EndpointAttributes      Endpoint = "GetAttributes"
+	EndpointSurveyTopics    Endpoint = "GetSurveyTopics"
+	EndpointSources         Endpoint = "GetSources"
+	EndpointRoles           Endpoint = "Roles"
+)
+
+// QueryFields lists the fields an endpoint supports for filtering and sorting.
+type QueryFields struct {
+	Filter []QueryField
+	Sort   []QueryField
+}
+
+// queryFields is the registry of the fields each endpoint supports, as listed
+// for its filter and sort parameters in the Demand API reference.
+var queryFields = map[Endpoint]QueryFields{
+	EndpointProjects: {
+		Filter: []QueryField{QueryFieldExtProjectID, QueryFieldTitle, QueryFieldState, QueryFieldJobNumber,
+			QueryFieldCreatedAt, QueryFieldUpdatedAt, QueryFieldStateLastUpdatedAt},
+		Sort: []QueryField{QueryFieldExtProjectID, QueryFieldTitle, QueryFieldState, QueryFieldJobNumber,
+			QueryFieldCreatedAt, QueryFieldUpdatedAt, QueryFieldStateLastUpdatedAt},
+	},
+	EndpointLineItems: {
+		Filter: []QueryField{QueryFieldExtLineItemID, QueryFieldTitle, QueryFieldState, QueryFieldStateReason,
+			QueryFieldCountryISOCode, QueryFieldLanguageISOCode, QueryFieldLaunchedAt, QueryFieldCreatedAt,
+			QueryFieldUpdatedAt},
+		Sort: []QueryField{QueryFieldExtLineItemID, QueryFieldTitle, QueryFieldState, QueryFieldCountryISOCode,
+			QueryFieldLanguageISOCode, QueryFieldLaunchedAt, QueryFieldCreatedAt, QueryFieldUpdatedAt},
+	},
+	EndpointFeasibility: {
+		Filter: []QueryField{QueryFieldExtLineItemID},
+	},
+	EndpointEvents: {
+		Filter: []QueryField{QueryFieldType, QueryFieldExtProjectID, QueryFieldExtLineItemID, QueryFieldCreatedAt},
+		Sort:   []QueryField{QueryFieldType, QueryFieldCreatedAt},
+	},
+	EndpointInvoicesSummary: {
+		Filter: []QueryField{QueryFieldStartDate, QueryFieldEndDate, QueryFieldBillingDate, QueryFieldExtProjectID},
+		Sort:   []QueryField{QueryFieldBillingDate, QueryFieldExtProjectID},
+	},
+	EndpointTemplates: {
+		Filter: []QueryField{QueryFieldID, QueryFieldName, QueryFieldCreatedAt, QueryFieldUpdatedAt},
+		Sort:   []QueryField{QueryFieldID, QueryFieldName, QueryFieldCreatedAt, QueryFieldUpdatedAt},
+	},
+	EndpointCountries: {
+		Filter: []QueryField{QueryFieldIsoCode, QueryFieldCountryName},
+		Sort:   []QueryField{QueryFieldIsoCode, QueryFieldCountryName},
+	},
+	EndpointAttributes: {
+		Filter: []QueryField{QueryFieldID, QueryFieldName, QueryFieldText, QueryFieldType,
+			QueryFieldIsAllowedInSurveyAppends},
+		Sort: []QueryField{QueryFieldID, QueryFieldName, QueryFieldText, QueryFieldType},
+	},
+	EndpointSurveyTopics: {
+		Filter: []QueryField{QueryFieldSurveyTopic},
+		Sort:   []QueryField{QueryFieldSurveyTopic},
+	},
+	EndpointSources: {
+		Filter: []QueryField{QueryFieldCountryISOCode, QueryFieldLanguageISOCode},
+		Sort:   []QueryField{QueryFieldCountryISOCode, QueryFieldLanguageISOCode},
+	},
+	EndpointRoles: {
+		Filter: []QueryField{QueryFieldID, QueryFieldName},
+		Sort:   []QueryField{QueryFieldID, QueryFieldName},
+	},
+}
+
+// SupportedQueryFields returns the fields endpoint supports for filtering and
+// sorting, and false if the endpoint is not in the registry.
+func SupportedQueryFields(endpoint Endpoint) (QueryFields, bool) {
+	f, ok := queryFields[endpoint]
+	return f, ok
+}
+
+// QueryFieldError is returned for QueryOptions that filter or sort by a field
+// the endpoint does not support.
+type QueryFieldError struct {
+	Endpoint Endpoint
+	Field    QueryField
+	// Sort is true if Field was used for sorting, false for filtering.
+	Sort bool
+	// Supported are the fields the endpoint supports for the same use.
+	Supported []QueryField
+}
+
+// Error ...
+func (e *QueryFieldError) Error() string {
+	use := "filter"
+	if e.Sort {
+		use = "sort"
+	}
+	return fmt.Sprintf("%s cannot %s by %q, supported fields: %v", e.Endpoint, use, e.Field, e.Supported)
+}
+
+// Is reports whether target is ErrUnsupportedQueryField.
+func (e *QueryFieldError) Is(target error) bool {
+	return target == ErrUnsupportedQueryField
+}
+
+// Validate returns a *QueryFieldError if o filters or sorts by a field that
+// endpoint does not support. Endpoints that are not in the registry accept
+// any field.
+func (o *QueryOptions) Validate(endpoint Endpoint) error {
+	fields, ok := queryFields[endpoint]
+	if o == nil || !ok {
+		return nil
+	}
+	for _, f := range o.FilterBy {
+		if f != nil && !hasQueryField(fields.Filter, f.Field) {
+			return &QueryFieldError{Endpoint: endpoint, Field: f.Field, Supported: fields.Filter}
+		}
+	}
+	for _, s := range o.SortBy {
+		if s != nil && !hasQueryField(fields.Sort, s.Field) {
+			return &QueryFieldError{Endpoint: endpoint, Field: s.Field, Sort: true, Supported: fields.Sort}
+		}
+	}
+	return nil
+}
+
+func hasQueryField(fields []QueryField, field QueryField) bool {
+	for _, f := range fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// validateQuery validates options for endpoint, unless the client skips query
+// validation.
+func (c *Client) validateQuery(endpoint Endpoint, options *QueryOptions) error {
+	if c.Options.SkipQueryValidation {
+		return nil
+	}
+	return options.Validate(endpoint)
+}
@@ -1,7 +1,11 @@
 package samplify_test
 
 import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	samplify "github.com/researchnow/go-samplifyapi-client/lib"
 )
@@ -36,3 +40,102 @@ func TestInSliceString(t *testing.T) {
 		}
 	}
 }
+
+func TestQueryBuilder(t *testing.T) {
+	from := time.Date(2019, time.June, 12, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query    *samplify.QueryOptions
+		expected string
+	}{
+		{
+			query:    samplify.NewQuery(),
+			expected: "",
+		},
+		{
+			query:    samplify.NewQuery().Filter(samplify.QueryFieldState, samplify.Eq(samplify.StateLaunched)).SortDesc(samplify.QueryFieldCreatedAt).Page(2, 100),
+			expected: "limit=100&offset=100&sort=createdAt%3Adesc&state=LAUNCHED",
+		},
+		{
+			query:    samplify.NewQuery().Filter(samplify.QueryFieldTitle, samplify.Eq("a&b=c")).Filter(samplify.QueryFieldID, samplify.Ints(1, 2)),
+			expected: "id=1%2C2&title=a%26b%3Dc",
+		},
+		{
+			query:    samplify.NewQuery().Filter(samplify.QueryFieldCreatedAt, samplify.DateRange(from, time.Time{})).Page(1, 5000),
+			expected: "createdAt=2019%2F06%2F12%2C&limit=1000",
+		},
+		{
+			query:    samplify.NewQuery().WithExtLineItemID("l1").WithEventType("a b").WithScope("all"),
+			expected: "eventType=a+b&extLineItemId=l1&scope=all",
+		},
+	}
+	for _, tt := range tests {
+		if q := tt.query.Encode(); q != tt.expected {
+			t.Errorf("expected %q, got %q", tt.expected, q)
+		}
+	}
+	if q := tests[3].query; q.Limit != 5000 {
+		t.Errorf("expected Encode not to change the limit, got %d", q.Limit)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	query := samplify.NewQuery().
+		Filter(samplify.QueryFieldState, samplify.States(samplify.StateLaunched, samplify.StatePaused)).
+		Filter(samplify.QueryFieldTitle, samplify.Eq("Samplify & co")).
+		SortAsc(samplify.QueryFieldTitle).
+		SortDesc(samplify.QueryFieldCreatedAt).
+		Page(3, 20).
+		WithExtProjectID("p1")
+	encoded := query.Encode()
+
+	parsed, err := samplify.ParseQuery("?" + encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Encode() != encoded {
+		t.Errorf("expected %q, got %q", encoded, parsed.Encode())
+	}
+	if parsed.Offset != 40 || parsed.Limit != 20 || *parsed.ExtProjectId != "p1" || len(parsed.SortBy) != 2 ||
+		parsed.SortBy[1].Direction != samplify.SortDirectionDesc {
+		t.Errorf("unexpected parsed query %+v", parsed)
+	}
+
+	if _, err := samplify.ParseQuery("sort=title"); err == nil {
+		t.Error("expected an error for an invalid sort")
+	}
+}
+
+func TestQueryValidation(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"data":[]}`))
+	}))
+	defer ts.Close()
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+	client.Auth = getAuth()
+
+	_, err := client.GetCountries(samplify.NewQuery().Filter(samplify.QueryFieldBillingDate, samplify.Eq("2020/01/01")))
+	var qerr *samplify.QueryFieldError
+	if !errors.As(err, &qerr) || qerr.Endpoint != samplify.EndpointCountries || qerr.Sort {
+		t.Errorf("expected a filter QueryFieldError, got %v", err)
+	}
+	_, err = client.GetEvents(samplify.NewQuery().SortAsc(samplify.QueryFieldJobNumber))
+	if !errors.Is(err, samplify.ErrUnsupportedQueryField) {
+		t.Errorf("expected ErrUnsupportedQueryField, got %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("expected no request to be sent, got %d", requests)
+	}
+
+	if _, err := client.GetAllProjects(samplify.NewQuery().Filter(samplify.QueryFieldState, samplify.Eq(samplify.StateLaunched)).SortDesc(samplify.QueryFieldCreatedAt)); err != nil {
+		t.Error(err)
+	}
+	client.Options.SkipQueryValidation = true
+	if _, err := client.GetEvents(samplify.NewQuery().SortAsc(samplify.QueryFieldJobNumber)); err != nil {
+		t.Error(err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
@@ -0,0 +1,221 @@
+package samplify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Quota plan builder errors, matched with errors.Is
+var (
+	ErrUnknownAttribute       = errors.New("unknown attribute")
+	ErrUnknownAttributeOption = errors.New("unknown attribute option")
+	ErrAttributeNotAllowed    = errors.New("attribute is not allowed")
+	ErrAttributeUnavailable   = errors.New("attribute is deprecated or inactive")
+)
+
+// attributeCatalogue looks up the attributes of a country and language, as
+// returned by GetAttributes.
+type attributeCatalogue struct {
+	list []*Attribute
+	byID map[string]*Attribute
+}
+
+func newAttributeCatalogue(attributes []*Attribute) *attributeCatalogue {
+	c := &attributeCatalogue{byID: map[string]*Attribute{}}
+	for _, a := range attributes {
+		if a == nil {
+			continue
+		}
+		c.list = append(c.list, a)
+		c.byID[a.ID] = a
+	}
+	return c
+}
+
+// named returns the attribute whose name, ignoring case, or ID is name.
+func (c *attributeCatalogue) named(name string) (*Attribute, error) {
+	for _, a := range c.list {
+		if strings.EqualFold(a.Name, name) {
+			return a, nil
+		}
+	}
+	if a, ok := c.byID[name]; ok {
+		return a, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownAttribute, name)
+}
+
+// optionIDs returns the IDs of the options of a whose text, ignoring case, or
+// ID is one of texts.
+func optionIDs(a *Attribute, texts []string) ([]string, error) {
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("no options of attribute %q: %w", a.Name, ErrRequiredFieldEmpty)
+	}
+	ids := make([]string, 0, len(texts))
+next:
+	for _, t := range texts {
+		for _, o := range a.Options {
+			if o != nil && (strings.EqualFold(o.Text, t) || o.ID == t) {
+				ids = append(ids, o.ID)
+				continue next
+			}
+		}
+		return nil, fmt.Errorf("%w %q of attribute %q", ErrUnknownAttributeOption, t, a.Name)
+	}
+	return ids, nil
+}
+
+// usable returns an error if the attribute a is deprecated or inactive.
+func usable(a *Attribute) error {
+	if a.State == StateDeprecated || a.State == StateInactive {
+		return fmt.Errorf("%w: %q is %s", ErrAttributeUnavailable, a.Name, a.State)
+	}
+	return nil
+}
+
+// QuotaPlanBuilder builds a QuotaPlan from the attributes of the country and
+// language of a line item, as returned by GetAttributes, referring to the
+// attributes by name and to their options by text:
+//
+//	b := samplify.NewQuotaPlanBuilder(attributes.List)
+//	b.Filter("AGE", "18-24", "25-34")
+//	b.QuotaGroup("Gender").
+//		Perc(50, samplify.Select("GENDER", "Male")).
+//		Perc(50, samplify.Select("GENDER", "Female"))
+//	plan, err := b.Build()
+//
+// The first error, such as an unknown attribute or option, is returned by
+// Build.
+type QuotaPlanBuilder struct {
+	catalogue *attributeCatalogue
+	plan      QuotaPlan
+	err       error
+}
+
+// NewQuotaPlanBuilder returns a builder of a quota plan using attributes.
+func NewQuotaPlanBuilder(attributes []*Attribute) *QuotaPlanBuilder {
+	return &QuotaPlanBuilder{catalogue: newAttributeCatalogue(attributes)}
+}
+
+// Filter restricts the respondents to those matching one of the options of
+// the attribute.
+func (b *QuotaPlanBuilder) Filter(attribute string, options ...string) *QuotaPlanBuilder {
+	return b.filter(OperatorInclude, attribute, options)
+}
+
+// Exclude excludes the respondents matching one of the options of the
+// attribute.
+func (b *QuotaPlanBuilder) Exclude(attribute string, options ...string) *QuotaPlanBuilder {
+	return b.filter(OperatorExclude, attribute, options)
+}
+
+func (b *QuotaPlanBuilder) filter(op Operator, attribute string, options []string) *QuotaPlanBuilder {
+	if b.err != nil {
+		return b
+	}
+	a, err := b.attribute(attribute, func(a *Attribute) bool { return a.IsAllowedInFilters }, "filters")
+	if err != nil {
+		b.err = err
+		return b
+	}
+	ids, err := optionIDs(a, options)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	b.plan.Filters = append(b.plan.Filters, &QuotaFilters{AttributeID: a.ID, Options: ids, Operator: &op})
+	return b
+}
+
+// attribute returns the named attribute if it is usable and allowed.
+func (b *QuotaPlanBuilder) attribute(name string, allowed func(*Attribute) bool, in string) (*Attribute, error) {
+	a, err := b.catalogue.named(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := usable(a); err != nil {
+		return nil, err
+	}
+	if !allowed(a) {
+		return nil, fmt.Errorf("%w in %s: %q", ErrAttributeNotAllowed, in, a.Name)
+	}
+	return a, nil
+}
+
+// QuotaGroup adds a quota group, whose cells are added with the returned
+// builder.
+func (b *QuotaPlanBuilder) QuotaGroup(name string) *QuotaGroupBuilder {
+	g := &QuotaGroup{Name: &name, QuotaCells: []*QuotaCell{}}
+	b.plan.QuotaGroups = append(b.plan.QuotaGroups, g)
+	return &QuotaGroupBuilder{b: b, group: g}
+}
+
+// Build returns the quota plan, or the first error of the builder or of
+// ValidateQuotaPlan.
+func (b *QuotaPlanBuilder) Build() (*QuotaPlan, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	plan := b.plan
+	if err := ValidateQuotaPlan(&plan); err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// Selection selects options of an attribute for a quota cell, see Select.
+type Selection struct {
+	Attribute string
+	Options   []string
+}
+
+// Select selects the options of the attribute, by text, for a quota cell.
+func Select(attribute string, options ...string) Selection {
+	return Selection{Attribute: attribute, Options: options}
+}
+
+// QuotaGroupBuilder adds the cells of a quota group of a QuotaPlanBuilder.
+// All the cells of a group must be allocated either by percentage or by count.
+type QuotaGroupBuilder struct {
+	b     *QuotaPlanBuilder
+	group *QuotaGroup
+}
+
+// Perc adds a cell of perc percent of the completes, for the respondents
+// matching all of selections.
+func (g *QuotaGroupBuilder) Perc(perc float64, selections ...Selection) *QuotaGroupBuilder {
+	return g.cell(&QuotaCell{Perc: &perc}, selections)
+}
+
+// Count adds a cell of count completes, for the respondents matching all of
+// selections.
+func (g *QuotaGroupBuilder) Count(count uint32, selections ...Selection) *QuotaGroupBuilder {
+	return g.cell(&QuotaCell{Count: &count}, selections)
+}
+
+func (g *QuotaGroupBuilder) cell(c *QuotaCell, selections []Selection) *QuotaGroupBuilder {
+	b := g.b
+	if b.err != nil {
+		return g
+	}
+	if len(selections) == 0 {
+		b.err = fmt.Errorf("no attributes for a cell of quota group %q: %w", *g.group.Name, ErrRequiredFieldEmpty)
+		return g
+	}
+	for _, s := range selections {
+		a, err := b.attribute(s.Attribute, func(a *Attribute) bool { return a.IsAllowedInQuotas }, "quotas")
+		if err != nil {
+			b.err = err
+			return g
+		}
+		ids, err := optionIDs(a, s.Options)
+		if err != nil {
+			b.err = err
+			return g
+		}
+		c.QuotaNodes = append(c.QuotaNodes, &QuotaNode{AttributeID: a.ID, Options: ids})
+	}
+	g.group.QuotaCells = append(g.group.QuotaCells, c)
+	return g
+}
@@ -0,0 +1,88 @@
+package samplify_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// testAttributes is a catalogue of attributes for the quota plan tests.
+func testAttributes() []*samplify.Attribute {
+	return []*samplify.Attribute{
+		{ID: "11", Name: "GENDER", IsAllowedInFilters: true, IsAllowedInQuotas: true, State: samplify.StateActive,
+			Options: []*samplify.AttributeOption{{ID: "1", Text: "Male"}, {ID: "2", Text: "Female"}}},
+		{ID: "12", Name: "AGE", IsAllowedInFilters: true, IsAllowedInQuotas: true, State: samplify.StateActive,
+			Options: []*samplify.AttributeOption{{ID: "18-24", Text: "18-24"}, {ID: "25-34", Text: "25-34"}, {ID: "35-99", Text: "35+"}}},
+		{ID: "13", Name: "POSTAL_CODE", IsAllowedInFilters: true, IsAllowedInQuotas: false, State: samplify.StateActive,
+			Options: []*samplify.AttributeOption{{ID: "10001", Text: "10001"}}},
+		{ID: "14", Name: "INCOME", IsAllowedInFilters: true, IsAllowedInQuotas: true, State: samplify.StateDeprecated,
+			Options: []*samplify.AttributeOption{{ID: "1", Text: "Low"}}},
+		{ID: "15", Name: "AGE_GENDER", IsAllowedInFilters: true, IsAllowedInQuotas: true, State: samplify.StateActive,
+			Options: []*samplify.AttributeOption{{ID: "1", Text: "Male 18-24"}}, Exclusions: []*string{strptr("11"), strptr("12")}},
+	}
+}
+
+func strptr(s string) *string { return &s }
+
+func TestQuotaPlanBuilder(t *testing.T) {
+	b := samplify.NewQuotaPlanBuilder(testAttributes())
+	b.Filter("postal_code", "10001").Exclude("AGE", "35+")
+	b.QuotaGroup("Gender").
+		Perc(40, samplify.Select("GENDER", "male")).
+		Perc(60, samplify.Select("GENDER", "Female"))
+	b.QuotaGroup("Age and gender").
+		Count(100, samplify.Select("AGE", "18-24", "25-34"), samplify.Select("GENDER", "Female"))
+	plan, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := samplify.ValidateQuotaPlan(plan); err != nil {
+		t.Errorf("built plan is invalid: %v", err)
+	}
+
+	if len(plan.Filters) != 2 || plan.Filters[0].AttributeID != "13" || *plan.Filters[0].Operator != samplify.OperatorInclude ||
+		*plan.Filters[1].Operator != samplify.OperatorExclude || !reflect.DeepEqual(plan.Filters[1].Options, []string{"35-99"}) {
+		t.Errorf("unexpected filters %+v", plan.Filters)
+	}
+	if len(plan.QuotaGroups) != 2 || *plan.QuotaGroups[0].Name != "Gender" || len(plan.QuotaGroups[0].QuotaCells) != 2 {
+		t.Fatalf("unexpected quota groups %+v", plan.QuotaGroups)
+	}
+	male := plan.QuotaGroups[0].QuotaCells[0]
+	if *male.Perc != 40 || male.Count != nil || male.QuotaNodes[0].AttributeID != "11" || male.QuotaNodes[0].Options[0] != "1" {
+		t.Errorf("unexpected cell %+v", male)
+	}
+	cell := plan.QuotaGroups[1].QuotaCells[0]
+	if *cell.Count != 100 || len(cell.QuotaNodes) != 2 || !reflect.DeepEqual(cell.QuotaNodes[0].Options, []string{"18-24", "25-34"}) {
+		t.Errorf("unexpected cell %+v", cell)
+	}
+}
+
+func TestQuotaPlanBuilderErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *samplify.QuotaPlanBuilder)
+		err   error
+	}{
+		{"unknown attribute", func(b *samplify.QuotaPlanBuilder) { b.Filter("HEIGHT", "tall") }, samplify.ErrUnknownAttribute},
+		{"unknown option", func(b *samplify.QuotaPlanBuilder) { b.Filter("GENDER", "Other") }, samplify.ErrUnknownAttributeOption},
+		{"no options", func(b *samplify.QuotaPlanBuilder) { b.Filter("GENDER") }, samplify.ErrRequiredFieldEmpty},
+		{"not allowed in quotas", func(b *samplify.QuotaPlanBuilder) {
+			b.QuotaGroup("Zip").Perc(100, samplify.Select("POSTAL_CODE", "10001"))
+		}, samplify.ErrAttributeNotAllowed},
+		{"deprecated", func(b *samplify.QuotaPlanBuilder) { b.Filter("INCOME", "Low") }, samplify.ErrAttributeUnavailable},
+		{"empty group", func(b *samplify.QuotaPlanBuilder) { b.QuotaGroup("Empty") }, samplify.ErrMissingQuotaCells},
+		{"mixed allocations", func(b *samplify.QuotaPlanBuilder) {
+			b.QuotaGroup("Gender").Perc(50, samplify.Select("GENDER", "Male")).Count(10, samplify.Select("GENDER", "Female"))
+		}, samplify.ErrInconsistentAllocationType},
+	}
+	for _, tt := range tests {
+		b := samplify.NewQuotaPlanBuilder(testAttributes())
+		tt.build(b)
+		plan, err := b.Build()
+		if !errors.Is(err, tt.err) || plan != nil {
+			t.Errorf("%s: expected %v, got %v, %+v", tt.name, tt.err, err, plan)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package samplify
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrInvalidQuotaPlan ... Matched by a *QuotaPlanError with errors.Is
+var ErrInvalidQuotaPlan = errors.New("invalid quota plan")
+
+// Quota plan violations, matched with errors.Is, along with
+// ErrMissingQuotaCells, ErrAllocationNotProvided, ErrAmbigiuosAllocation,
+// ErrInconsistentAllocationType and the quota plan builder errors.
+var (
+	ErrQuotaPercentageSum    = errors.New("quota cell percentages do not sum to 100")
+	ErrQuotaCountSum         = errors.New("quota cell counts do not sum to the required completes")
+	ErrOverlappingQuotaCells = errors.New("quota cells overlap")
+	ErrExclusiveAttributes   = errors.New("mutually exclusive attributes are combined")
+)
+
+// CheckQuotaPlanOptions configure CheckQuotaPlan.
+type CheckQuotaPlanOptions struct {
+	// RequiredCompletes of the line item, which the counts of every quota
+	// group allocated by count must sum to. Zero skips the check.
+	RequiredCompletes int64
+	// Attributes of the country and language of the line item, as returned
+	// by GetAttributes. Nil skips the checks of the attributes and options.
+	Attributes []*Attribute
+}
+
+// QuotaPlanViolation is a rule of quota plans broken by the value at Path.
+type QuotaPlanViolation struct {
+	// Path is the JSON path of the value in the plan, such as
+	// "quotaGroups[0].quotaCells[1].perc", or "" for the plan itself.
+	Path string
+	Err  error
+}
+
+// Error ...
+func (v *QuotaPlanViolation) Error() string {
+	if len(v.Path) == 0 {
+		return v.Err.Error()
+	}
+	return v.Path + ": " + v.Err.Error()
+}
+
+// Unwrap returns the error of v.
+func (v *QuotaPlanViolation) Unwrap() error {
+	return v.Err
+}
+
+// QuotaPlanError is returned by CheckQuotaPlan with all the violations
+// found in a quota plan.
+type QuotaPlanError struct {
+	Violations []*QuotaPlanViolation
+}
+
+// Error ...
+func (e *QuotaPlanError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrInvalidQuotaPlan, strings.Join(msgs, "; "))
+}
+
+// Is reports whether target is ErrInvalidQuotaPlan or the error of one of the
+// violations.
+func (e *QuotaPlanError) Is(target error) bool {
+	if target == ErrInvalidQuotaPlan {
+		return true
+	}
+	for _, v := range e.Violations {
+		if errors.Is(v.Err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckQuotaPlan checks plan beyond ValidateQuotaPlan: the percentages of a
+// quota group sum to 100 and its counts to the required completes, the cells
+// of a group do not overlap, and the attributes and options exist in the
+// catalogue, can be used in filters or quotas and are not combined with
+// mutually exclusive attributes. It returns a *QuotaPlanError listing all
+// the violations, or nil.
+func CheckQuotaPlan(plan *QuotaPlan, opts *CheckQuotaPlanOptions) error {
+	if plan == nil {
+		return nil
+	}
+	if opts == nil {
+		opts = &CheckQuotaPlanOptions{}
+	}
+	c := &quotaPlanChecker{opts: opts, used: map[string]string{}}
+	if opts.Attributes != nil {
+		c.catalogue = newAttributeCatalogue(opts.Attributes)
+	}
+	for i, f := range plan.Filters {
+		if f == nil {
+			continue
+		}
+		path := fmt.Sprintf("filters[%d]", i)
+		c.checkAttribute(path, f.AttributeID, f.Options, "filters", func(a *Attribute) bool { return a.IsAllowedInFilters })
+	}
+	for i, g := range plan.QuotaGroups {
+		if g != nil {
+			c.checkGroup(fmt.Sprintf("quotaGroups[%d]", i), g)
+		}
+	}
+	c.checkExclusions()
+	if len(c.violations) == 0 {
+		return nil
+	}
+	return &QuotaPlanError{Violations: c.violations}
+}
+
+// quotaPlanChecker collects the violations of a quota plan.
+type quotaPlanChecker struct {
+	opts       *CheckQuotaPlanOptions
+	catalogue  *attributeCatalogue
+	violations []*QuotaPlanViolation
+	// used maps the IDs of the attributes of the plan to the path of their
+	// first use, and uses lists them in the order of first use.
+	used map[string]string
+	uses []string
+}
+
+func (c *quotaPlanChecker) add(path string, err error) {
+	c.violations = append(c.violations, &QuotaPlanViolation{Path: path, Err: err})
+}
+
+func (c *quotaPlanChecker) checkGroup(path string, g *QuotaGroup) {
+	if len(g.QuotaCells) == 0 {
+		c.add(path+".quotaCells", ErrMissingQuotaCells)
+		return
+	}
+	var perc float64
+	var count int64
+	alloc := Allocation("")
+	consistent := true
+	for j, cell := range g.QuotaCells {
+		cellPath := fmt.Sprintf("%s.quotaCells[%d]", path, j)
+		if cell == nil {
+			c.add(cellPath, ErrAllocationNotProvided)
+			consistent = false
+			continue
+		}
+		switch {
+		case cell.Perc == nil && cell.Count == nil:
+			c.add(cellPath, ErrAllocationNotProvided)
+			consistent = false
+		case cell.Perc != nil && cell.Count != nil:
+			c.add(cellPath, ErrAmbigiuosAllocation)
+			consistent = false
+		case alloc != "" && cell.AllocationType() != alloc:
+			c.add(cellPath, ErrInconsistentAllocationType)
+			consistent = false
+		case cell.Perc != nil:
+			alloc = AllocationPercentage
+			perc += *cell.Perc
+		default:
+			alloc = AllocationCount
+			count += int64(*cell.Count)
+		}
+		for k, n := range cell.QuotaNodes {
+			if n != nil {
+				nodePath := fmt.Sprintf("%s.quotaNodes[%d]", cellPath, k)
+				c.checkAttribute(nodePath, n.AttributeID, n.Options, "quotas", func(a *Attribute) bool { return a.IsAllowedInQuotas })
+			}
+		}
+	}
+	if consistent {
+		switch {
+		case alloc == AllocationPercentage && math.Abs(perc-100) > 1e-6:
+			c.add(path, fmt.Errorf("%w: %v", ErrQuotaPercentageSum, perc))
+		case alloc == AllocationCount && c.opts.RequiredCompletes > 0 && count != c.opts.RequiredCompletes:
+			c.add(path, fmt.Errorf("%w: %d, want %d", ErrQuotaCountSum, count, c.opts.RequiredCompletes))
+		}
+	}
+	c.checkOverlaps(path, g.QuotaCells)
+}
+
+// checkOverlaps reports the cells that a respondent could match along with
+// an earlier cell of the group: for each attribute the cells share, they
+// share an option. Cells on different attributes, such as {GENDER: Male} and
+// {AGE: 18-24}, overlap.
+func (c *quotaPlanChecker) checkOverlaps(path string, cells []*QuotaCell) {
+	selected := make([]map[string]map[string]bool, len(cells))
+	for j, cell := range cells {
+		if cell != nil {
+			selected[j] = cellOptions(cell)
+		}
+	}
+	for k := range cells {
+		for j := 0; j < k; j++ {
+			if selected[j] != nil && selected[k] != nil && overlap(selected[j], selected[k]) {
+				c.add(fmt.Sprintf("%s.quotaCells[%d]", path, k), fmt.Errorf("%w with quotaCells[%d]", ErrOverlappingQuotaCells, j))
+				break
+			}
+		}
+	}
+}
+
+// cellOptions returns the options selected by cell for each attribute.
+func cellOptions(cell *QuotaCell) map[string]map[string]bool {
+	m := map[string]map[string]bool{}
+	for _, n := range cell.QuotaNodes {
+		if n == nil {
+			continue
+		}
+		opts := map[string]bool{}
+		for _, o := range n.Options {
+			// Nodes on the same attribute must all match.
+			if prev, ok := m[n.AttributeID]; !ok || prev[o] {
+				opts[o] = true
+			}
+		}
+		m[n.AttributeID] = opts
+	}
+	return m
+}
+
+// overlap reports whether a respondent can match the options of both a and
+// b. An attribute of only one of them does not constrain the other.
+func overlap(a, b map[string]map[string]bool) bool {
+	for attr, opts := range a {
+		other, ok := b[attr]
+		if !ok {
+			other = opts
+		}
+		common := false
+		for o := range opts {
+			if other[o] {
+				common = true
+				break
+			}
+		}
+		if !common {
+			return false
+		}
+	}
+	for _, opts := range b {
+		// A cell that no respondent can match overlaps no other.
+		if len(opts) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// checkAttribute checks the attribute and options at path against the
+// catalogue, and records the use of the attribute.
+func (c *quotaPlanChecker) checkAttribute(path, id string, options []string, in string, allowed func(*Attribute) bool) {
+	if _, ok := c.used[id]; !ok {
+		c.used[id] = path + ".attributeId"
+		c.uses = append(c.uses, id)
+	}
+	if c.catalogue == nil {
+		return
+	}
+	a, ok := c.catalogue.byID[id]
+	if !ok {
+		c.add(path+".attributeId", fmt.Errorf("%w %q", ErrUnknownAttribute, id))
+		return
+	}
+	if err := usable(a); err != nil {
+		c.add(path+".attributeId", err)
+	} else if !allowed(a) {
+		c.add(path+".attributeId", fmt.Errorf("%w in %s: %q", ErrAttributeNotAllowed, in, a.Name))
+	}
+	valid := map[string]bool{}
+	for _, o := range a.Options {
+		if o != nil {
+			valid[o.ID] = true
+		}
+	}
+	for k, o := range options {
+		if !valid[o] {
+			c.add(fmt.Sprintf("%s.options[%d]", path, k), fmt.Errorf("%w %q of attribute %q", ErrUnknownAttributeOption, o, a.Name))
+		}
+	}
+}
+
+// checkExclusions reports the attributes used along with an attribute
+// listing them in its exclusions, at the first use of the later one.
+func (c *quotaPlanChecker) checkExclusions() {
+	if c.catalogue == nil {
+		return
+	}
+	order := map[string]int{}
+	for i, id := range c.uses {
+		order[id] = i
+	}
+	reported := map[[2]string]bool{}
+	for _, id := range c.uses {
+		a, ok := c.catalogue.byID[id]
+		if !ok {
+			continue
+		}
+		for _, ex := range a.Exclusions {
+			if ex == nil || *ex == id {
+				continue
+			}
+			j, ok := order[*ex]
+			if !ok {
+				continue
+			}
+			first, later := id, *ex
+			if j < order[id] {
+				first, later = later, first
+			}
+			if reported[[2]string{first, later}] {
+				continue
+			}
+			reported[[2]string{first, later}] = true
+			c.add(c.used[later], fmt.Errorf("%w: %s and %s", ErrExclusiveAttributes, c.name(first), c.name(later)))
+		}
+	}
+}
+
+// name returns the name of the attribute id, or id if it is unknown.
+func (c *quotaPlanChecker) name(id string) string {
+	if a, ok := c.catalogue.byID[id]; ok && len(a.Name) > 0 {
+		return fmt.Sprintf("%q", a.Name)
+	}
+	return fmt.Sprintf("%q", id)
+}
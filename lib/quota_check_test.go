@@ -0,0 +1,111 @@
+package samplify_test
+
+import (
+	"errors"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+func TestCheckQuotaPlan(t *testing.T) {
+	b := samplify.NewQuotaPlanBuilder(testAttributes())
+	b.Filter("AGE", "18-24", "25-34")
+	b.QuotaGroup("Gender").
+		Perc(40, samplify.Select("GENDER", "Male")).
+		Perc(60, samplify.Select("GENDER", "Female"))
+	b.QuotaGroup("Age and gender").
+		Count(60, samplify.Select("AGE", "18-24"), samplify.Select("GENDER", "Female")).
+		Count(40, samplify.Select("AGE", "18-24"), samplify.Select("GENDER", "Male"))
+	plan, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &samplify.CheckQuotaPlanOptions{RequiredCompletes: 100, Attributes: testAttributes()}
+	if err := samplify.CheckQuotaPlan(plan, opts); err != nil {
+		t.Errorf("unexpected error for a valid plan: %v", err)
+	}
+
+	opts.RequiredCompletes = 200
+	err = samplify.CheckQuotaPlan(plan, opts)
+	if !errors.Is(err, samplify.ErrQuotaCountSum) || !errors.Is(err, samplify.ErrInvalidQuotaPlan) {
+		t.Errorf("expected a count sum violation, got %v", err)
+	}
+}
+
+func TestCheckQuotaPlanViolations(t *testing.T) {
+	perc := func(f float64) *float64 { return &f }
+	count := func(n uint32) *uint32 { return &n }
+	name := "g"
+	plan := &samplify.QuotaPlan{
+		Filters: []*samplify.QuotaFilters{
+			{AttributeID: "99", Options: []string{"1"}},
+			{AttributeID: "12", Options: []string{"18-24", "65+"}},
+		},
+		QuotaGroups: []*samplify.QuotaGroup{
+			{Name: &name, QuotaCells: []*samplify.QuotaCell{
+				{Perc: perc(50), QuotaNodes: []*samplify.QuotaNode{{AttributeID: "11", Options: []string{"1", "2"}}}},
+				{Perc: perc(40), QuotaNodes: []*samplify.QuotaNode{{AttributeID: "11", Options: []string{"2"}}}},
+			}},
+			{Name: &name, QuotaCells: []*samplify.QuotaCell{
+				{Count: count(10), QuotaNodes: []*samplify.QuotaNode{{AttributeID: "13", Options: []string{"10001"}}}},
+				{QuotaNodes: []*samplify.QuotaNode{{AttributeID: "15", Options: []string{"1"}}}},
+			}},
+		},
+	}
+	err := samplify.CheckQuotaPlan(plan, &samplify.CheckQuotaPlanOptions{RequiredCompletes: 10, Attributes: testAttributes()})
+	var qerr *samplify.QuotaPlanError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("expected a *QuotaPlanError, got %v", err)
+	}
+	want := []struct {
+		path string
+		err  error
+	}{
+		{"filters[0].attributeId", samplify.ErrUnknownAttribute},
+		{"filters[1].options[1]", samplify.ErrUnknownAttributeOption},
+		{"quotaGroups[0]", samplify.ErrQuotaPercentageSum},
+		{"quotaGroups[0].quotaCells[1]", samplify.ErrOverlappingQuotaCells},
+		{"quotaGroups[1].quotaCells[0].quotaNodes[0].attributeId", samplify.ErrAttributeNotAllowed},
+		{"quotaGroups[1].quotaCells[1]", samplify.ErrAllocationNotProvided},
+		{"quotaGroups[1].quotaCells[1]", samplify.ErrOverlappingQuotaCells},
+		{"quotaGroups[1].quotaCells[1].quotaNodes[0].attributeId", samplify.ErrExclusiveAttributes},
+		{"quotaGroups[1].quotaCells[1].quotaNodes[0].attributeId", samplify.ErrExclusiveAttributes},
+	}
+	if len(qerr.Violations) != len(want) {
+		t.Fatalf("expected %d violations, got %d: %v", len(want), len(qerr.Violations), err)
+	}
+	for i, w := range want {
+		v := qerr.Violations[i]
+		if v.Path != w.path || !errors.Is(v, w.err) {
+			t.Errorf("violation %d: expected %s at %s, got %v", i, w.err, w.path, v)
+		}
+	}
+}
+
+func TestCheckQuotaPlanOverlaps(t *testing.T) {
+	perc := func(f float64) *float64 { return &f }
+	cell := func(nodes ...*samplify.QuotaNode) *samplify.QuotaCell {
+		return &samplify.QuotaCell{Perc: perc(50), QuotaNodes: nodes}
+	}
+	male := &samplify.QuotaNode{AttributeID: "11", Options: []string{"1"}}
+	female := &samplify.QuotaNode{AttributeID: "11", Options: []string{"2"}}
+	young := &samplify.QuotaNode{AttributeID: "12", Options: []string{"18-24"}}
+	tests := []struct {
+		name    string
+		a, b    *samplify.QuotaCell
+		overlap bool
+	}{
+		{"same attribute, different options", cell(male), cell(female), false},
+		{"different attributes", cell(male), cell(young), true},
+		{"shared attribute with different options", cell(male, young), cell(female), false},
+		{"shared attribute with common options", cell(male, young), cell(male), true},
+	}
+	for _, tt := range tests {
+		name := "g"
+		plan := &samplify.QuotaPlan{QuotaGroups: []*samplify.QuotaGroup{{Name: &name, QuotaCells: []*samplify.QuotaCell{tt.a, tt.b}}}}
+		err := samplify.CheckQuotaPlan(plan, nil)
+		if errors.Is(err, samplify.ErrOverlappingQuotaCells) != tt.overlap {
+			t.Errorf("%s: expected overlap %v, got %v", tt.name, tt.overlap, err)
+		}
+	}
+}
@@ -10,8 +10,6 @@ import (
 	"mime/multipart"
 	"net/http"
 	"time"
-
-	"github.com/hashicorp/go-retryablehttp"
 )
 
 // APIResponse ...
@@ -22,8 +20,10 @@ type APIResponse struct {
 
 // SendRequestWithContext exposing sendrequest to enable custom requests
 func SendRequestWithContext(ctx context.Context, host, method, url, accessToken string, body interface{}, timeout int) (*APIResponse, error) {
-	opt := &extraOptions{}
-	return sendRequest(ctx, host, method, url, accessToken, body, timeout, false, opt)
+	client := &http.Client{
+		Timeout: time.Second * time.Duration(timeout),
+	}
+	return sendRequest(ctx, client, host, method, url, accessToken, body)
 }
 
 // SendRequest exposing sendrequest to enable custom requests
@@ -31,8 +31,7 @@ func SendRequest(host, method, url, accessToken string, body interface{}, timeou
 	return SendRequestWithContext(context.Background(), host, method, url, accessToken, body, timeout)
 }
 
-func sendRequest(ctx context.Context, host, method, url, accessToken string, body interface{}, timeout int, disableRetry bool, opt *extraOptions) (*APIResponse, error) {
-	// log.WithFields(log.Fields{"module": "go-samplifyapi-client", "function": "sendRequest", "URL": fmt.Sprintf("%s%s", host, url), "Method": method}).Info()
+func sendRequest(ctx context.Context, client *http.Client, host, method, url, accessToken string, body interface{}) (*APIResponse, error) {
 	jstr, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
@@ -45,32 +44,16 @@ func sendRequest(ctx context.Context, host, method, url, accessToken string, bod
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-type", "application/json")
 	req = req.WithContext(ctx)
+	setIdempotencyKey(ctx, req)
 	if len(accessToken) > 0 {
 		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", accessToken))
 	}
 
-	var resp *http.Response
-	if !disableRetry && opt != nil && opt.retryEnabled {
-
-		retryableClient := retryablehttp.NewClient()
-
-		dur := time.Duration(timeout)
-		retryableClient.HTTPClient.Timeout = time.Second * dur
-
-		retryableClient.RetryMax = opt.maxRetries
-		retryableClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
-
-		resp, err = retryableClient.StandardClient().Do(req)
-	} else {
-		dur := time.Duration(timeout)
-		client := &http.Client{
-			Timeout: time.Second * dur,
-		}
-		resp, err = client.Do(req)
-	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	bodyjson, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -81,36 +64,19 @@ func sendRequest(ctx context.Context, host, method, url, accessToken string, bod
 		RequestID: resp.Header.Get("x-request-id"),
 	}
 	if resp.StatusCode >= http.StatusBadRequest {
-		t := time.Now()
-		errPath := fmt.Sprintf("%s%s", host, url)
-		err := &ErrorResponse{
-			Timestamp:  &t,
-			RequestID:  ar.RequestID,
-			HTTPCode:   resp.StatusCode,
-			HTTPPhrase: resp.Status,
-			Path:       errPath,
-			Errors:     []*Error{{Path: errPath, Message: resp.Status}},
-		}
+		err := newErrorResponse(resp, fmt.Sprintf("%s%s", host, url), bodyjson)
 		ar.Body = json.RawMessage(bodyjson)
-		// log.WithFields(log.Fields{"module": "go-samplifyapi-client", "function": "sendRequest", "respBody": ar.Body}).Info(err)
-		// log.WithFields(log.Fields{"module": "go-samplifyapi-client", "function": "sendRequest"}).Info(err)
 		return ar, err
 	}
 	ar.Body = json.RawMessage(bodyjson)
 	return ar, err
 }
 
-func sendFormData(ctx context.Context, host, method, path, accessToken string, file multipart.File, fileName string, message string, timeout int) (*APIResponse, error) {
-	// log.WithFields(log.Fields{"module": "go-samplifyapi-client", "function": "sendFormData", "URL": fmt.Sprintf("%s%s", host, path), "Method": method}).Info()
-	dur := time.Duration(timeout)
-	client := &http.Client{
-		Timeout: time.Second * dur,
-	}
+func sendFormData(ctx context.Context, client *http.Client, host, method, path, accessToken string, file multipart.File, fileName string, message string) (*APIResponse, error) {
 	bodyBuf := &bytes.Buffer{}
 	bodyWriter := multipart.NewWriter(bodyBuf)
 	fileWriter, err := bodyWriter.CreateFormFile("file", fileName)
 	if err != nil {
-		// log.WithFields(log.Fields{"module": "go-samplifyapi-client", "function": "sendFormData", "Method": method}).Error(err)
 		return nil, err
 	}
 	_, err = io.Copy(fileWriter, file)
@@ -128,10 +94,12 @@ func sendFormData(ctx context.Context, host, method, path, accessToken string, f
 	}
 	req.Header.Add("Content-Type", bodyWriter.FormDataContentType())
 	req = req.WithContext(ctx)
+	setIdempotencyKey(ctx, req)
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
 
 	bodyjson, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
@@ -142,18 +110,8 @@ func sendFormData(ctx context.Context, host, method, path, accessToken string, f
 		RequestID: resp.Header.Get("x-request-id"),
 	}
 	if resp.StatusCode >= http.StatusBadRequest {
-		t := time.Now()
-		errPath := fmt.Sprintf("%s%s", host, path)
-		err := &ErrorResponse{
-			Timestamp:  &t,
-			RequestID:  ar.RequestID,
-			HTTPCode:   resp.StatusCode,
-			HTTPPhrase: resp.Status,
-			Path:       errPath,
-			Errors:     []*Error{{Path: errPath, Message: resp.Status}},
-		}
+		err := newErrorResponse(resp, fmt.Sprintf("%s%s", host, path), bodyjson)
 		ar.Body = json.RawMessage(bodyjson)
-		// log.WithFields(log.Fields{"module": "go-samplifyapi-client", "function": "sendFormData"}).Error(err)
 		return ar, err
 	}
 	ar.Body = json.RawMessage(bodyjson)
@@ -16,12 +16,30 @@ type ProjectResponse struct {
 	ResponseStatus ResponseStatus `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *ProjectResponse) Envelope() *Envelope[*Project] {
+	return &Envelope[*Project]{Data: r.Project, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *ProjectResponse) setEnvelope(e *Envelope[*Project]) {
+	r.Project, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // BuyProjectResponse represents the response from Buy Project request
 type BuyProjectResponse struct {
 	List           []*BuyProjectLineItem `json:"data"`
 	ResponseStatus ResponseStatus        `json:"status"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *BuyProjectResponse) Envelope() *ListEnvelope[*BuyProjectLineItem] {
+	return &ListEnvelope[*BuyProjectLineItem]{Data: r.List, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *BuyProjectResponse) setEnvelope(e *ListEnvelope[*BuyProjectLineItem]) {
+	r.List, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // GetAllProjectsResponse ...
 type GetAllProjectsResponse struct {
 	Projects       []*ProjectHeader `json:"data"`
@@ -29,33 +47,82 @@ type GetAllProjectsResponse struct {
 	Meta           Meta             `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *GetAllProjectsResponse) Envelope() *ListEnvelope[*ProjectHeader] {
+	return &ListEnvelope[*ProjectHeader]{Data: r.Projects, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *GetAllProjectsResponse) setEnvelope(e *ListEnvelope[*ProjectHeader]) {
+	r.Projects, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // ProjectReportResponse ...
 type ProjectReportResponse struct {
 	Report         *ProjectReport `json:"data"`
 	ResponseStatus ResponseStatus `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *ProjectReportResponse) Envelope() *Envelope[*ProjectReport] {
+	return &Envelope[*ProjectReport]{Data: r.Report, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *ProjectReportResponse) setEnvelope(e *Envelope[*ProjectReport]) {
+	r.Report, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // CloseProjectResponse ...
 type CloseProjectResponse struct {
-	Project *struct {
-		ProjectHeader
-		LineItems []*LineItemHeader `json:"lineItems"`
-	} `json:"data"`
+	Project        *ClosedProject `json:"data"`
 	ResponseStatus ResponseStatus `json:"status"`
 }
 
+// ClosedProject is the project returned by CloseProject, with the headers of
+// its line items.
+type ClosedProject = struct {
+	ProjectHeader
+	LineItems []*LineItemHeader `json:"lineItems"`
+}
+
+// Envelope returns r as an Envelope.
+func (r *CloseProjectResponse) Envelope() *Envelope[*ClosedProject] {
+	return &Envelope[*ClosedProject]{Data: r.Project, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *CloseProjectResponse) setEnvelope(e *Envelope[*ClosedProject]) {
+	r.Project, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // LineItemResponse ... Response returned by Add, Update and Get LineItem requests
 type LineItemResponse struct {
 	Item           *LineItem      `json:"data"`
 	ResponseStatus ResponseStatus `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *LineItemResponse) Envelope() *Envelope[*LineItem] {
+	return &Envelope[*LineItem]{Data: r.Item, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *LineItemResponse) setEnvelope(e *Envelope[*LineItem]) {
+	r.Item, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // UpdateLineItemStateResponse ...
 type UpdateLineItemStateResponse struct {
 	LineItem       *LineItem      `json:"data"`
 	ResponseStatus ResponseStatus `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *UpdateLineItemStateResponse) Envelope() *Envelope[*LineItem] {
+	return &Envelope[*LineItem]{Data: r.LineItem, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *UpdateLineItemStateResponse) setEnvelope(e *Envelope[*LineItem]) {
+	r.LineItem, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // LineItemListItem ...
 type LineItemListItem struct {
 	Model
@@ -75,14 +142,36 @@ type GetAllLineItemsResponse struct {
 	Meta           Meta                `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *GetAllLineItemsResponse) Envelope() *ListEnvelope[*LineItemListItem] {
+	return &ListEnvelope[*LineItemListItem]{Data: r.List, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *GetAllLineItemsResponse) setEnvelope(e *ListEnvelope[*LineItemListItem]) {
+	r.List, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // GetFeasibilityResponse ...
 type GetFeasibilityResponse struct {
-	List []*struct {
-		ExtLineItemID string       `json:"extLineItemId"`
-		Feasibility   *Feasibility `json:"feasibility"`
-		Quote         Quote        `json:"quote"`
-	} `json:"data"`
-	ResponseStatus ResponseStatus `json:"status"`
+	List           []*LineItemFeasibility `json:"data"`
+	ResponseStatus ResponseStatus         `json:"status"`
+}
+
+// LineItemFeasibility is the feasibility and quote of a line item returned by
+// GetFeasibility.
+type LineItemFeasibility = struct {
+	ExtLineItemID string       `json:"extLineItemId"`
+	Feasibility   *Feasibility `json:"feasibility"`
+	Quote         Quote        `json:"quote"`
+}
+
+// Envelope returns r as a ListEnvelope.
+func (r *GetFeasibilityResponse) Envelope() *ListEnvelope[*LineItemFeasibility] {
+	return &ListEnvelope[*LineItemFeasibility]{Data: r.List, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *GetFeasibilityResponse) setEnvelope(e *ListEnvelope[*LineItemFeasibility]) {
+	r.List, r.ResponseStatus = e.Data, e.ResponseStatus
 }
 
 // GetCountriesResponse ...
@@ -92,6 +181,15 @@ type GetCountriesResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *GetCountriesResponse) Envelope() *ListEnvelope[*Country] {
+	return &ListEnvelope[*Country]{Data: r.List, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *GetCountriesResponse) setEnvelope(e *ListEnvelope[*Country]) {
+	r.List, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // GetAttributesResponse ...
 type GetAttributesResponse struct {
 	List           []*Attribute   `json:"data"`
@@ -99,6 +197,15 @@ type GetAttributesResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *GetAttributesResponse) Envelope() *ListEnvelope[*Attribute] {
+	return &ListEnvelope[*Attribute]{Data: r.List, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *GetAttributesResponse) setEnvelope(e *ListEnvelope[*Attribute]) {
+	r.List, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // GetSurveyTopicsResponse ...
 type GetSurveyTopicsResponse struct {
 	List           []*SurveyTopic `json:"data"`
@@ -106,6 +213,15 @@ type GetSurveyTopicsResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *GetSurveyTopicsResponse) Envelope() *ListEnvelope[*SurveyTopic] {
+	return &ListEnvelope[*SurveyTopic]{Data: r.List, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *GetSurveyTopicsResponse) setEnvelope(e *ListEnvelope[*SurveyTopic]) {
+	r.List, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // GetEventListResponse ...
 type GetEventListResponse struct {
 	List           []*Event       `json:"data"`
@@ -113,12 +229,30 @@ type GetEventListResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *GetEventListResponse) Envelope() *ListEnvelope[*Event] {
+	return &ListEnvelope[*Event]{Data: r.List, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *GetEventListResponse) setEnvelope(e *ListEnvelope[*Event]) {
+	r.List, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // GetEventResponse ...
 type GetEventResponse struct {
 	Event          *Event         `json:"data"`
 	ResponseStatus ResponseStatus `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *GetEventResponse) Envelope() *Envelope[*Event] {
+	return &Envelope[*Event]{Data: r.Event, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *GetEventResponse) setEnvelope(e *Envelope[*Event]) {
+	r.Event, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // DetailedProjectReportResponse ...
 type DetailedProjectReportResponse struct {
 	Report         DetailedProjectReport `json:"data"`
@@ -126,6 +260,15 @@ type DetailedProjectReportResponse struct {
 	Meta           Meta                  `json:"meta"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *DetailedProjectReportResponse) Envelope() *Envelope[DetailedProjectReport] {
+	return &Envelope[DetailedProjectReport]{Data: r.Report, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *DetailedProjectReportResponse) setEnvelope(e *Envelope[DetailedProjectReport]) {
+	r.Report, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // DetailedLineItemReportResponse ...
 type DetailedLineItemReportResponse struct {
 	Report         DetailedLineItemReport `json:"data"`
@@ -133,6 +276,15 @@ type DetailedLineItemReportResponse struct {
 	Meta           Meta                   `json:"meta"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *DetailedLineItemReportResponse) Envelope() *Envelope[DetailedLineItemReport] {
+	return &Envelope[DetailedLineItemReport]{Data: r.Report, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *DetailedLineItemReportResponse) setEnvelope(e *Envelope[DetailedLineItemReport]) {
+	r.Report, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // StudyMetadataResponse ...
 type StudyMetadataResponse struct {
 	StudyMetadata  StudyMetadata  `json:"data"`
@@ -140,6 +292,15 @@ type StudyMetadataResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *StudyMetadataResponse) Envelope() *Envelope[StudyMetadata] {
+	return &Envelope[StudyMetadata]{Data: r.StudyMetadata, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *StudyMetadataResponse) setEnvelope(e *Envelope[StudyMetadata]) {
+	r.StudyMetadata, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // QuotaCellResponse ...
 type QuotaCellResponse struct {
 	QuotaCell      QuotaCell      `json:"data"`
@@ -147,6 +308,15 @@ type QuotaCellResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *QuotaCellResponse) Envelope() *Envelope[QuotaCell] {
+	return &Envelope[QuotaCell]{Data: r.QuotaCell, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *QuotaCellResponse) setEnvelope(e *Envelope[QuotaCell]) {
+	r.QuotaCell, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // OrderDetailResponseData ...
 type OrderDetailResponse struct {
 	OrderDetail    OrderDetail    `json:"data"`
@@ -154,6 +324,15 @@ type OrderDetailResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *OrderDetailResponse) Envelope() *Envelope[OrderDetail] {
+	return &Envelope[OrderDetail]{Data: r.OrderDetail, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *OrderDetailResponse) setEnvelope(e *Envelope[OrderDetail]) {
+	r.OrderDetail, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // CheckOrderNumber ...
 type CheckOrderNumberResponse struct {
 	CheckOrderNumber CheckOrderNumber `json:"data"`
@@ -161,6 +340,15 @@ type CheckOrderNumberResponse struct {
 	Meta             Meta             `json:"meta"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *CheckOrderNumberResponse) Envelope() *Envelope[CheckOrderNumber] {
+	return &Envelope[CheckOrderNumber]{Data: r.CheckOrderNumber, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *CheckOrderNumberResponse) setEnvelope(e *Envelope[CheckOrderNumber]) {
+	r.CheckOrderNumber, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 type CheckOrderNumber struct {
 	Availability bool `json:"availability"`
 }
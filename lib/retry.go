@@ -0,0 +1,296 @@
+package samplify
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header that carries the idempotency key of a
+// request, see WithIdempotencyKey.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// Retry policy defaults
+const (
+	defaultRetryInitialBackoff = time.Second
+	defaultRetryMaxBackoff     = 30 * time.Second
+	defaultRetryMultiplier     = 2.0
+	defaultRetryJitter         = 0.5
+)
+
+// RetryPolicy configures how a Client retries failed requests. Requests with
+// an idempotent method (GET, HEAD, OPTIONS, PUT, DELETE) are retried by
+// default. Other requests, such as the POST requests that create or buy a
+// project, are only retried if they carry an idempotency key, see
+// WithIdempotencyKey.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts per request, including
+	// the first one. Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Defaults to 1s.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Defaults to 30s.
+	MaxBackoff time.Duration
+	// Multiplier is the factor by which the delay grows after each attempt.
+	// Defaults to 2.
+	Multiplier float64
+	// Jitter is the fraction of each delay that is randomized, between 0
+	// and 1. Defaults to 0.5, i.e. delays vary between 50% and 100% of the
+	// exponential backoff. Use a negative value to disable jitter.
+	Jitter float64
+	// MaxElapsedTime, if not zero, bounds the total time spent on a request
+	// including all its attempts and delays. No retry is made that would
+	// exceed it.
+	MaxElapsedTime time.Duration
+	// RetryOn reports whether an attempt should be retried. By default,
+	// network errors and the responses with status 429, 500, 502, 503 and 504
+	// are retried.
+	RetryOn func(resp *http.Response, err error) bool
+	// Rules override MaxAttempts for specific requests. The first matching
+	// rule applies.
+	Rules []RetryRule
+	// OnRetry, if not nil, is called before each retry.
+	OnRetry func(RetryEvent)
+}
+
+// RetryRule overrides the number of attempts for the requests it matches.
+type RetryRule struct {
+	// Method matches the HTTP method of the request. Empty matches any method.
+	Method string
+	// Path, if not nil, matches the URL path of the request.
+	Path *regexp.Regexp
+	// MaxAttempts for the matching requests. Use 1 to disable retries.
+	MaxAttempts int
+}
+
+// RetryEvent describes a failed attempt that is about to be retried.
+type RetryEvent struct {
+	Request *http.Request
+	// Attempt is the number of the failed attempt, starting at 1.
+	Attempt int
+	// StatusCode of the failed attempt, or 0 if it failed with Err.
+	StatusCode int
+	Err        error
+	// Delay before the next attempt.
+	Delay time.Duration
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey returns a context that makes requests sent with it carry
+// key in the Idempotency-Key header. This allows the retry policy to retry
+// requests with a non-idempotent method, such as CreateProject or BuyProject.
+// Use a new key for every distinct operation.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// setIdempotencyKey sets the idempotency key of ctx, if any, on req.
+func setIdempotencyKey(ctx context.Context, req *http.Request) {
+	if key, ok := ctx.Value(idempotencyKey{}).(string); ok && len(key) > 0 {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+}
+
+// maxAttempts returns the number of attempts allowed for req.
+func (p *RetryPolicy) maxAttempts(req *http.Request) int {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+	default:
+		if len(req.Header.Get(IdempotencyKeyHeader)) == 0 {
+			return 1
+		}
+	}
+	for _, r := range p.Rules {
+		if (len(r.Method) == 0 || r.Method == req.Method) &&
+			(r.Path == nil || r.Path.MatchString(req.URL.Path)) {
+			return r.MaxAttempts
+		}
+	}
+	return p.MaxAttempts
+}
+
+// retryable reports whether an attempt that ended with resp and err should be
+// retried.
+func (p *RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if p.RetryOn != nil {
+		return p.RetryOn(resp, err)
+	}
+	if err != nil {
+		return !isContextErr(err)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff returns the delay after the given failed attempt. A Retry-After
+// header on resp takes precedence over the exponential backoff.
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			return d
+		}
+	}
+	initial, max := p.InitialBackoff, p.MaxBackoff
+	if initial <= 0 {
+		initial = defaultRetryInitialBackoff
+	}
+	if max <= 0 {
+		max = defaultRetryMaxBackoff
+	}
+	multiplier, jitter := p.Multiplier, p.Jitter
+	if multiplier < 1 {
+		multiplier = defaultRetryMultiplier
+	}
+	if jitter == 0 {
+		jitter = defaultRetryJitter
+	}
+
+	d := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if d > float64(max) {
+		d = float64(max)
+	}
+	if jitter > 0 {
+		jitterMu.Lock()
+		r := jitterRand.Float64()
+		jitterMu.Unlock()
+		d -= d * math.Min(jitter, 1) * r
+	}
+	return time.Duration(d)
+}
+
+// retryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	if len(v) == 0 {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// retryTransport retries requests sent with client according to policy. It is
+// used as the transport of an outer http.Client so that the timeout of client
+// applies to each attempt rather than to all of them.
+type retryTransport struct {
+	client  *http.Client
+	policy  *RetryPolicy
+	logger  Logger
+	metrics Metrics
+	// tracer, if not nil, traces each retry in a span named "Retry" that
+	// covers the delay and the attempt that follows it.
+	tracer Tracer
+}
+
+// RoundTrip sends req, retrying it according to the policy.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	start := time.Now()
+	max := t.policy.maxAttempts(req)
+	// The span of the current retry, and the context of its attempt.
+	var span Span
+	attemptCtx := ctx
+	endSpan := func(err error) {
+		if span != nil {
+			if err != nil {
+				span.RecordError(err)
+			}
+			span.End()
+			span = nil
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		r := req.Clone(withAttempt(attemptCtx, attempt))
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				endSpan(err)
+				return nil, err
+			}
+			r.Body = body
+		}
+		// The request is sent by client, not by this transport.
+		r.RequestURI = ""
+
+		resp, err := t.client.Do(r)
+		if ue, ok := err.(*url.Error); ok {
+			// The outer http.Client wraps the error again.
+			err = ue.Err
+		}
+		if err == nil && resp.StatusCode >= http.StatusBadRequest {
+			endSpan(&httpStatusError{status: resp.Status})
+		} else {
+			endSpan(err)
+		}
+		if attempt >= max || !t.policy.retryable(resp, err) {
+			return resp, err
+		}
+		delay := t.policy.backoff(attempt, resp)
+		if t.policy.MaxElapsedTime > 0 && time.Since(start)+delay > t.policy.MaxElapsedTime {
+			return resp, err
+		}
+		ev := RetryEvent{Request: req, Attempt: attempt, Err: err, Delay: delay}
+		if resp != nil {
+			ev.StatusCode = resp.StatusCode
+		}
+		if t.logger != nil {
+			args := []interface{}{"method", req.Method, "path", req.URL.Path,
+				"attempt", attempt, "status", ev.StatusCode, "delay", delay}
+			if err != nil {
+				args = append(args, "error", err)
+			}
+			t.logger.Warn("samplify retrying request", args...)
+		}
+		if t.metrics != nil {
+			t.metrics.ObserveRetry(operationOf(ctx))
+		}
+		if t.policy.OnRetry != nil {
+			t.policy.OnRetry(ev)
+		}
+		if resp != nil {
+			// Drain the body so that the connection can be reused.
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if t.tracer != nil {
+			attemptCtx, span = t.tracer.Start(ctx, "Retry")
+			span.SetAttribute(AttrAttempt, attempt+1)
+			span.SetAttribute(AttrRetryDelay, delay.Seconds())
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			endSpan(ctx.Err())
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
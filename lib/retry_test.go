@@ -0,0 +1,142 @@
+package samplify_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// flakyServer fails the first n requests with status and counts all requests.
+func flakyServer(n int32, status int, header http.Header) (*httptest.Server, *int32) {
+	var calls int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= n {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"data":{},"status":{"message":"success"}}`))
+	}))
+	return ts, &calls
+}
+
+func retryClient(url string, policy *samplify.RetryPolicy) *samplify.Client {
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: url, Retry: policy})
+	client.Auth = getAuth()
+	return client
+}
+
+func TestRetryIdempotentRequest(t *testing.T) {
+	ts, calls := flakyServer(2, http.StatusServiceUnavailable, nil)
+	defer ts.Close()
+
+	var events []samplify.RetryEvent
+	client := retryClient(ts.URL, &samplify.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+		OnRetry:        func(ev samplify.RetryEvent) { events = append(events, ev) },
+	})
+	if _, err := client.GetProjectBy("p1"); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected 3 attempts, got %d", *calls)
+	}
+	if len(events) != 2 || events[1].Attempt != 2 || events[1].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("unexpected retry events %+v", events)
+	}
+}
+
+func TestRetryUnsafeRequest(t *testing.T) {
+	policy := &samplify.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+	update := &samplify.UpdateProjectCriteria{ExtProjectID: "p1"}
+
+	ts, calls := flakyServer(1, http.StatusBadGateway, nil)
+	defer ts.Close()
+	_, err := retryClient(ts.URL, policy).UpdateProject(update)
+	if !errors.Is(err, samplify.ErrServer) || *calls != 1 {
+		t.Errorf("expected a single failed attempt without idempotency key, got %d: %v", *calls, err)
+	}
+
+	ts, calls = flakyServer(1, http.StatusBadGateway, nil)
+	defer ts.Close()
+	var key string
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{
+		APIBaseURL: ts.URL,
+		Retry:      policy,
+		Middleware: []samplify.Middleware{func(next http.RoundTripper) http.RoundTripper {
+			return samplify.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				key = req.Header.Get(samplify.IdempotencyKeyHeader)
+				return next.RoundTrip(req)
+			})
+		}},
+	})
+	client.Auth = getAuth()
+	ctx := samplify.WithIdempotencyKey(context.Background(), "update-p1")
+	if _, err := client.UpdateProjectWithContext(ctx, update); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 2 || key != "update-p1" {
+		t.Errorf("expected a retry with the idempotency key, got %d attempts with key %q", *calls, key)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	ts, calls := flakyServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}})
+	defer ts.Close()
+
+	var delay time.Duration
+	client := retryClient(ts.URL, &samplify.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+		OnRetry:        func(ev samplify.RetryEvent) { delay = ev.Delay },
+	})
+	start := time.Now()
+	if _, err := client.GetProjectBy("p1"); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != 2 || delay != time.Second || time.Since(start) < time.Second {
+		t.Errorf("expected Retry-After to be honored, got %d attempts after %v", *calls, delay)
+	}
+}
+
+func TestRetryLimits(t *testing.T) {
+	ts, calls := flakyServer(10, http.StatusInternalServerError, nil)
+	defer ts.Close()
+
+	client := retryClient(ts.URL, &samplify.RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: 50 * time.Millisecond,
+		Multiplier:     1,
+		Jitter:         -1,
+		MaxElapsedTime: 125 * time.Millisecond,
+	})
+	if _, err := client.GetProjectBy("p1"); !errors.Is(err, samplify.ErrServer) {
+		t.Errorf("expected ErrServer, got %v", err)
+	}
+	if *calls != 3 {
+		t.Errorf("expected MaxElapsedTime to allow 3 attempts, got %d", *calls)
+	}
+
+	atomic.StoreInt32(calls, 0)
+	client = retryClient(ts.URL, &samplify.RetryPolicy{
+		MaxAttempts:    10,
+		InitialBackoff: time.Millisecond,
+		Rules: []samplify.RetryRule{
+			{Method: "GET", Path: regexp.MustCompile(`^/projects/`), MaxAttempts: 2},
+		},
+	})
+	client.GetProjectBy("p1")
+	if *calls != 2 {
+		t.Errorf("expected the rule to allow 2 attempts, got %d", *calls)
+	}
+}
@@ -6,6 +6,15 @@ type RolesResponse struct {
 	Meta  Meta   `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *RolesResponse) Envelope() *ListEnvelope[Role] {
+	return &ListEnvelope[Role]{Data: r.Roles, Meta: r.Meta}
+}
+
+func (r *RolesResponse) setEnvelope(e *ListEnvelope[Role]) {
+	r.Roles, r.Meta = e.Data, e.Meta
+}
+
 // Role holds the information about a user role and the actions that can be performed for that role
 type Role struct {
 	ID              string          `json:"id"`
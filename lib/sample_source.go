@@ -26,3 +26,12 @@ type GetSampleSourceResponse struct {
 	ResponseStatus ResponseStatus  `json:"status"`
 	Meta           Meta            `json:"meta"`
 }
+
+// Envelope returns r as a ListEnvelope.
+func (r *GetSampleSourceResponse) Envelope() *ListEnvelope[*SampleSource] {
+	return &ListEnvelope[*SampleSource]{Data: r.List, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *GetSampleSourceResponse) setEnvelope(e *ListEnvelope[*SampleSource]) {
+	r.List, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
@@ -0,0 +1,562 @@
+package samplifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// EventLineItemStateChanged is the type of the events the server records when
+// the state of a line item changes.
+const EventLineItemStateChanged samplify.EventType = "LineItem:StateChanged"
+
+// defaultPageSize is the page size of list responses without a limit.
+const defaultPageSize = 10
+
+// apiError is an error response of the API.
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func errorf(status int, code, format string, args ...interface{}) *apiError {
+	return &apiError{status: status, code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// result is what a handler of the API returns: the data of the response, its
+// meta for list responses, or an error.
+type result struct {
+	data interface{}
+	meta *meta
+	err  *apiError
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request, rt route, body []byte) {
+	// Handlers run with the lock held and the response is encoded before it
+	// is released, so that the data is not changed while it is written.
+	res, b, err := func() (result, []byte, error) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		res := s.handleAPI(r, rt, body)
+		if res.err != nil {
+			return res, nil, nil
+		}
+		env := map[string]interface{}{
+			"data":   res.data,
+			"status": samplify.ResponseStatus{Message: "success", Errors: []samplify.ErrorInfo{}},
+		}
+		if res.meta != nil {
+			env["meta"] = res.meta
+		}
+		b, err := json.Marshal(env)
+		return res, b, err
+	}()
+
+	switch {
+	case res.err != nil:
+		writeError(w, res.err.status, res.err.code, res.err.message)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(b)
+	}
+}
+
+func notFound(r *http.Request) result {
+	return result{err: errorf(http.StatusNotFound, "NOT_FOUND", "no route for %s %s", r.Method, r.URL.Path)}
+}
+
+func (s *Server) handleAPI(r *http.Request, rt route, body []byte) result {
+	p := rt.parts
+	switch {
+	case p[0] == "projects":
+		return s.handleProjects(r, p[1:], body)
+	case p[0] == "events":
+		return s.handleEvents(r, p[1:])
+	case len(p) >= 2 && p[0] == "templates" && p[1] == "quotaPlan":
+		return s.handleTemplates(r, p[2:], body)
+	}
+	return notFound(r)
+}
+
+func (s *Server) handleProjects(r *http.Request, p []string, body []byte) result {
+	if len(p) == 0 {
+		switch r.Method {
+		case http.MethodGet:
+			return s.listProjects(r)
+		case http.MethodPost:
+			return s.createProject(body)
+		}
+		return notFound(r)
+	}
+	project, ok := s.projects[p[0]]
+	if !ok {
+		return result{err: errorf(http.StatusNotFound, "PROJECT_NOT_FOUND", "project %s not found", p[0])}
+	}
+	switch {
+	case len(p) == 1 && r.Method == http.MethodGet:
+		return result{data: project}
+	case len(p) == 1 && r.Method == http.MethodPost:
+		return s.updateProject(project, body)
+	case len(p) == 2 && p[1] == "buy" && r.Method == http.MethodPost:
+		return s.buyProject(project, body)
+	case len(p) == 2 && p[1] == "close" && r.Method == http.MethodPost:
+		return s.closeProject(project)
+	case len(p) == 2 && p[1] == "report" && r.Method == http.MethodGet:
+		return s.projectReport(project)
+	case len(p) == 2 && p[1] == "feasibility" && r.Method == http.MethodGet:
+		return s.feasibility(r, project)
+	case len(p) == 2 && p[1] == "permissions" && r.Method == http.MethodGet:
+		return result{data: s.projectPermissions(project)}
+	case len(p) == 2 && p[1] == "permissions" && r.Method == http.MethodPost:
+		return s.upsertPermissions(project, body)
+	case len(p) == 2 && p[1] == "lineItems" && r.Method == http.MethodGet:
+		return s.listLineItems(r, project)
+	case len(p) == 2 && p[1] == "lineItems" && r.Method == http.MethodPost:
+		return s.addLineItem(project, body)
+	case len(p) >= 3 && p[1] == "lineItems":
+		item := findLineItem(project, p[2])
+		if item == nil {
+			return result{err: errorf(http.StatusNotFound, "LINE_ITEM_NOT_FOUND", "line item %s not found", p[2])}
+		}
+		switch {
+		case len(p) == 3 && r.Method == http.MethodGet:
+			return result{data: item}
+		case len(p) == 3 && r.Method == http.MethodPost:
+			return s.updateLineItem(project, item, body)
+		case len(p) == 4 && r.Method == http.MethodPost:
+			return s.changeLineItemState(project, item, samplify.Action(p[3]))
+		}
+	}
+	return notFound(r)
+}
+
+func (s *Server) createProject(body []byte) result {
+	project := &samplify.Project{}
+	if err := json.Unmarshal(body, project); err != nil {
+		return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "%v", err)}
+	}
+	if len(project.ExtProjectID) == 0 {
+		return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "extProjectId is required")}
+	}
+	if _, ok := s.projects[project.ExtProjectID]; ok {
+		return result{err: errorf(http.StatusConflict, "PROJECT_EXISTS", "project %s already exists", project.ExtProjectID)}
+	}
+	now := samplify.CustomTime{Time: time.Now()}
+	project.Model = samplify.Model{CreatedAt: now, UpdatedAt: now, StateLastUpdatedAt: &now}
+	project.State = samplify.StateProvisioned
+	seen := map[string]bool{}
+	items := make([]*samplify.LineItem, 0, len(project.LineItems))
+	for _, item := range project.LineItems {
+		if item == nil {
+			continue
+		}
+		if seen[item.ExtLineItemID] {
+			return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "duplicate line item %s", item.ExtLineItemID)}
+		}
+		seen[item.ExtLineItemID] = true
+		initLineItem(item, now)
+		items = append(items, item)
+	}
+	project.LineItems = items
+	s.projects[project.ExtProjectID] = project
+	s.projectIDs = append(s.projectIDs, project.ExtProjectID)
+	return result{data: project}
+}
+
+func initLineItem(item *samplify.LineItem, now samplify.CustomTime) {
+	item.Model = samplify.Model{CreatedAt: now, UpdatedAt: now, StateLastUpdatedAt: &now}
+	item.State = samplify.StateProvisioned
+	item.LaunchedAt = nil
+}
+
+func (s *Server) updateProject(project *samplify.Project, body []byte) result {
+	var update struct {
+		LineItems []json.RawMessage `json:"lineItems"`
+	}
+	if err := json.Unmarshal(body, &update); err != nil {
+		return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "%v", err)}
+	}
+	items := make([]*samplify.LineItem, 0, len(update.LineItems))
+	raws := make([]json.RawMessage, 0, len(update.LineItems))
+	for _, raw := range update.LineItems {
+		if string(raw) == "null" {
+			continue
+		}
+		var id struct {
+			ExtLineItemID string `json:"extLineItemId"`
+		}
+		json.Unmarshal(raw, &id)
+		item := findLineItem(project, id.ExtLineItemID)
+		if item == nil {
+			return result{err: errorf(http.StatusNotFound, "LINE_ITEM_NOT_FOUND", "line item %s not found", id.ExtLineItemID)}
+		}
+		if !item.IsUpdateable() {
+			return result{err: errorf(http.StatusConflict, "LINE_ITEM_NOT_UPDATEABLE", "line item %s cannot be updated in state %s",
+				item.ExtLineItemID, item.State)}
+		}
+		items, raws = append(items, item), append(raws, raw)
+	}
+	// The line items are updated one by one instead of replacing the list.
+	lineItems, header := project.LineItems, project.ProjectHeader
+	project.LineItems = nil
+	json.Unmarshal(body, project)
+	project.LineItems = lineItems
+	project.State, project.Model = header.State, header.Model
+	for i, item := range items {
+		updateLineItem(item, raws[i])
+	}
+	project.UpdatedAt = samplify.CustomTime{Time: time.Now()}
+	delete(s.polls, project.ExtProjectID)
+	return result{data: project}
+}
+
+func updateLineItem(item *samplify.LineItem, raw []byte) {
+	header := item.LineItemHeader
+	json.Unmarshal(raw, item)
+	item.LineItemHeader = header
+	item.UpdatedAt = samplify.CustomTime{Time: time.Now()}
+}
+
+func (s *Server) buyProject(project *samplify.Project, body []byte) result {
+	var criteria []*samplify.BuyProjectCriteria
+	if err := json.Unmarshal(body, &criteria); err != nil {
+		return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "%v", err)}
+	}
+	buy := make([]*samplify.BuyProjectCriteria, 0, len(criteria))
+	for _, b := range criteria {
+		if b == nil {
+			continue
+		}
+		buy = append(buy, b)
+		item := findLineItem(project, b.ExtLineItemID)
+		if item == nil {
+			return result{err: errorf(http.StatusNotFound, "LINE_ITEM_NOT_FOUND", "line item %s not found", b.ExtLineItemID)}
+		}
+		if !item.IsBuyable() {
+			return result{err: errorf(http.StatusConflict, "LINE_ITEM_NOT_BUYABLE", "line item %s cannot be bought in state %s",
+				item.ExtLineItemID, item.State)}
+		}
+	}
+	bought := make([]*samplify.BuyProjectLineItem, 0, len(buy))
+	for _, b := range buy {
+		item := findLineItem(project, b.ExtLineItemID)
+		item.SurveyURL = b.SurveyURL
+		item.SurveyTestURL = b.SurveyTestURL
+		s.setLineItemState(project, item, samplify.StateAwaitingApproval)
+		bought = append(bought, &samplify.BuyProjectLineItem{ExtLineItemID: item.ExtLineItemID, State: item.State})
+	}
+	s.setProjectState(project, samplify.StateAwaitingApproval)
+	return result{data: bought}
+}
+
+func (s *Server) closeProject(project *samplify.Project) result {
+	headers := make([]*samplify.LineItemHeader, 0, len(project.LineItems))
+	for _, item := range project.LineItems {
+		if item.State.Allows(samplify.ActionClosed) {
+			s.setLineItemState(project, item, samplify.StateClosed)
+		}
+		headers = append(headers, &item.LineItemHeader)
+	}
+	s.setProjectState(project, samplify.StateClosed)
+	now := samplify.CustomTime{Time: time.Now()}
+	project.ClosedAt = &now
+	return result{data: &struct {
+		samplify.ProjectHeader
+		LineItems []*samplify.LineItemHeader `json:"lineItems"`
+	}{project.ProjectHeader, headers}}
+}
+
+func (s *Server) changeLineItemState(project *samplify.Project, item *samplify.LineItem, action samplify.Action) result {
+	if samplify.ValidateAction(action) != nil {
+		return result{err: errorf(http.StatusNotFound, "NOT_FOUND", "unknown action %s", action)}
+	}
+	next, err := item.State.Next(action)
+	if err != nil {
+		return result{err: errorf(http.StatusConflict, "INVALID_STATE_TRANSITION", "%v", err)}
+	}
+	s.setLineItemState(project, item, next)
+	if next == samplify.StateLaunched && project.State != samplify.StateLaunched {
+		s.setProjectState(project, samplify.StateLaunched)
+		project.LaunchedAt = item.LaunchedAt
+	}
+	return result{data: item}
+}
+
+// setLineItemState changes the state of item and records an event for it.
+func (s *Server) setLineItemState(project *samplify.Project, item *samplify.LineItem, state samplify.State) {
+	if item.State == state {
+		return
+	}
+	now := samplify.CustomTime{Time: time.Now()}
+	s.recordEvent(&samplify.Event{
+		EventType:     EventLineItemStateChanged,
+		ExtProjectID:  project.ExtProjectID,
+		ExtLineItemID: item.ExtLineItemID,
+		Resource: &samplify.EventResource{
+			Status: &samplify.EventStatusValues{
+				NewValue:      samplify.EventStatus(state),
+				PreviousValue: samplify.EventStatus(item.State),
+			},
+		},
+		CreatedAt: now,
+	})
+	item.State = state
+	item.StateLastUpdatedAt = &now
+	item.UpdatedAt = now
+	if state == samplify.StateLaunched && item.LaunchedAt == nil {
+		item.LaunchedAt = &now
+	}
+}
+
+func (s *Server) setProjectState(project *samplify.Project, state samplify.State) {
+	now := samplify.CustomTime{Time: time.Now()}
+	project.State = state
+	project.StateLastUpdatedAt = &now
+	project.UpdatedAt = now
+}
+
+func (s *Server) listProjects(r *http.Request) result {
+	q := r.URL.Query()
+	headers := []*samplify.ProjectHeader{}
+	for _, id := range s.projectIDs {
+		p := s.projects[id]
+		if matchesQuery(q, "extProjectId", p.ExtProjectID) && matchesQuery(q, "title", p.Title) &&
+			matchesQuery(q, "state", string(p.State)) && matchesQuery(q, "jobNumber", p.JobNumber) {
+			headers = append(headers, &p.ProjectHeader)
+		}
+	}
+	start, end, m := paginate(r, len(headers))
+	return result{data: headers[start:end], meta: m}
+}
+
+func (s *Server) listLineItems(r *http.Request, project *samplify.Project) result {
+	q := r.URL.Query()
+	items := []*samplify.LineItem{}
+	for _, item := range project.LineItems {
+		if matchesQuery(q, "extLineItemId", item.ExtLineItemID) && matchesQuery(q, "title", item.Title) &&
+			matchesQuery(q, "state", string(item.State)) && matchesQuery(q, "countryISOCode", item.CountryISOCode) {
+			items = append(items, item)
+		}
+	}
+	start, end, m := paginate(r, len(items))
+	return result{data: items[start:end], meta: m}
+}
+
+func (s *Server) addLineItem(project *samplify.Project, body []byte) result {
+	item := &samplify.LineItem{}
+	if err := json.Unmarshal(body, item); err != nil {
+		return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "%v", err)}
+	}
+	if findLineItem(project, item.ExtLineItemID) != nil {
+		return result{err: errorf(http.StatusConflict, "LINE_ITEM_EXISTS", "line item %s already exists", item.ExtLineItemID)}
+	}
+	initLineItem(item, samplify.CustomTime{Time: time.Now()})
+	project.LineItems = append(project.LineItems, item)
+	delete(s.polls, project.ExtProjectID)
+	return result{data: item}
+}
+
+func (s *Server) updateLineItem(project *samplify.Project, item *samplify.LineItem, body []byte) result {
+	if !item.IsUpdateable() {
+		return result{err: errorf(http.StatusConflict, "LINE_ITEM_NOT_UPDATEABLE", "line item %s cannot be updated in state %s",
+			item.ExtLineItemID, item.State)}
+	}
+	updateLineItem(item, body)
+	delete(s.polls, project.ExtProjectID)
+	return result{data: item}
+}
+
+// feasibilityItem is an entry of the feasibility response.
+type feasibilityItem struct {
+	ExtLineItemID string                `json:"extLineItemId"`
+	Feasibility   *samplify.Feasibility `json:"feasibility"`
+	Quote         samplify.Quote        `json:"quote"`
+}
+
+// feasibility reports the line items of project as PROCESSING for the
+// configured number of polls, and READY afterwards.
+func (s *Server) feasibility(r *http.Request, project *samplify.Project) result {
+	s.polls[project.ExtProjectID]++
+	ready := s.polls[project.ExtProjectID] > s.feasibilityPolls
+	q := r.URL.Query()
+	list := []*feasibilityItem{}
+	for _, item := range project.LineItems {
+		if !matchesQuery(q, "extLineItemId", item.ExtLineItemID) {
+			continue
+		}
+		f := &feasibilityItem{ExtLineItemID: item.ExtLineItemID, Feasibility: &samplify.Feasibility{
+			Status: samplify.FeasibilityStatusProcessing,
+		}}
+		if ready {
+			cpi := item.CostPerInterview
+			if cpi == 0 {
+				cpi = 2.5
+			}
+			f.Feasibility = &samplify.Feasibility{
+				Status:           samplify.FeasibilityStatusReady,
+				CostPerInterview: cpi,
+				Currency:         "USD",
+				Feasible:         true,
+				TotalCount:       item.RequiredCompletes * 10,
+			}
+			f.Quote = samplify.Quote{
+				CostPerUnit:   cpi,
+				Currency:      "USD",
+				EstimatedCost: cpi * float64(item.RequiredCompletes),
+			}
+		}
+		list = append(list, f)
+	}
+	return result{data: list}
+}
+
+func (s *Server) projectReport(project *samplify.Project) result {
+	report := &samplify.ProjectReport{
+		ExtProjectID: project.ExtProjectID,
+		Title:        project.Title,
+		JobNumber:    project.JobNumber,
+		State:        project.State,
+		CurrencyCode: "USD",
+		LineItems:    []*samplify.LineItemReport{},
+	}
+	for _, item := range project.LineItems {
+		report.RemainingCompletes += item.RequiredCompletes
+		report.LineItems = append(report.LineItems, &samplify.LineItemReport{
+			ExtLineItemID:      item.ExtLineItemID,
+			Title:              item.Title,
+			CountryISOCode:     item.CountryISOCode,
+			LanguageISOCode:    item.LanguageISOCode,
+			State:              item.State,
+			StateReason:        item.StateReason,
+			CurrencyCode:       "USD",
+			RemainingCompletes: item.RequiredCompletes,
+		})
+	}
+	return result{data: report}
+}
+
+func (s *Server) projectPermissions(project *samplify.Project) *samplify.ProjectPermissions {
+	perm, ok := s.permissions[project.ExtProjectID]
+	if !ok {
+		perm = &samplify.ProjectPermissions{
+			ExtProjectID: project.ExtProjectID,
+			CurrentUser:  samplify.CurrentUser{Roles: []string{"PROJECT_OWNER"}},
+			Users:        []samplify.UserData{},
+			Teams:        []samplify.TeamData{},
+		}
+		s.permissions[project.ExtProjectID] = perm
+	}
+	return perm
+}
+
+func (s *Server) upsertPermissions(project *samplify.Project, body []byte) result {
+	var criteria samplify.UpsertPermissionsCriteria
+	if err := json.Unmarshal(body, &criteria); err != nil {
+		return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "%v", err)}
+	}
+	perm := s.projectPermissions(project)
+	if criteria.UserPermissions != nil {
+		for _, u := range *criteria.UserPermissions {
+			if u == nil {
+				continue
+			}
+			found := false
+			for i := range perm.Users {
+				if perm.Users[i].ID == u.ID {
+					perm.Users[i].Role = u.Role
+					found = true
+				}
+			}
+			if !found {
+				perm.Users = append(perm.Users, samplify.UserData{ID: u.ID, Role: u.Role})
+			}
+		}
+	}
+	if criteria.TeamPermissions != nil {
+		for _, t := range *criteria.TeamPermissions {
+			if t == nil {
+				continue
+			}
+			for _, id := range t.ID {
+				if !hasTeam(perm.Teams, id) {
+					perm.Teams = append(perm.Teams, samplify.TeamData{ID: id})
+				}
+			}
+		}
+	}
+	return result{data: perm}
+}
+
+func hasTeam(teams []samplify.TeamData, id int32) bool {
+	for _, t := range teams {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func findLineItem(project *samplify.Project, extLineItemID string) *samplify.LineItem {
+	for _, item := range project.LineItems {
+		if item.ExtLineItemID == extLineItemID {
+			return item
+		}
+	}
+	return nil
+}
+
+// matchesQuery reports whether value is one of the comma separated values of
+// the query parameter key, or the parameter is not set.
+func matchesQuery(q map[string][]string, key, value string) bool {
+	param, ok := q[key]
+	if !ok || len(param) == 0 {
+		return true
+	}
+	for _, v := range strings.Split(param[0], ",") {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// paginate returns the bounds of the page of n items requested by r, and its
+// meta.
+func paginate(r *http.Request, n int) (int, int, *meta) {
+	q := r.URL.Query()
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	limit, err := strconv.Atoi(q.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	start, end := offset, offset+limit
+	if start > n {
+		start = n
+	}
+	if end > n {
+		end = n
+	}
+	links := map[string]string{"self": pageURL(r, offset, limit)}
+	if end < n {
+		links["next"] = pageURL(r, end, limit)
+	}
+	return start, end, &meta{Links: links, Total: n, PageSize: limit}
+}
+
+func pageURL(r *http.Request, offset, limit int) string {
+	q := r.URL.Query()
+	q.Set("offset", strconv.Itoa(offset))
+	q.Set("limit", strconv.Itoa(limit))
+	return r.URL.Path + "?" + q.Encode()
+}
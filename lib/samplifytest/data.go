@@ -0,0 +1,188 @@
+package samplifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// SetFeasibilityPolls sets the number of requests for the feasibility of a
+// project that report its line items as PROCESSING before they are READY. It
+// counts again from zero when the line items of the project change. The
+// default is 1.
+func (s *Server) SetFeasibilityPolls(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feasibilityPolls = n
+}
+
+// Project returns a copy of the project with the given ID, and false if there
+// is none.
+func (s *Server) Project(extProjectID string) (*samplify.Project, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[extProjectID]
+	if !ok {
+		return nil, false
+	}
+	b, _ := json.Marshal(p)
+	cp := &samplify.Project{}
+	json.Unmarshal(b, cp)
+	return cp, true
+}
+
+// SetLineItemState sets the state of a line item directly, e.g. to approve a
+// bought line item as the review by the API would. It records an event like
+// other state changes.
+func (s *Server) SetLineItemState(extProjectID, extLineItemID string, state samplify.State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.projects[extProjectID]
+	if !ok {
+		return fmt.Errorf("project %s not found", extProjectID)
+	}
+	item := findLineItem(p, extLineItemID)
+	if item == nil {
+		return fmt.Errorf("line item %s not found in project %s", extLineItemID, extProjectID)
+	}
+	s.setLineItemState(p, item, state)
+	return nil
+}
+
+// AddEvent records e as the latest event and returns its ID. Reprice events
+// without actions get URLs to accept and reject them on the server.
+func (s *Server) AddEvent(e *samplify.Event) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e.EventType == samplify.EventLineItemRepriceTriggered && e.Actions == nil {
+		base := fmt.Sprintf("%s%s/events/%d", s.URL, APIPrefix, s.nextEventID+1)
+		e.Actions = &samplify.EventActions{AcceptURL: base + "/accept", RejectURL: base + "/reject"}
+	}
+	return s.recordEvent(e)
+}
+
+func (s *Server) recordEvent(e *samplify.Event) int64 {
+	s.nextEventID++
+	e.EventID = s.nextEventID
+	if !e.CreatedAt.IsSet() {
+		e.CreatedAt = samplify.CustomTime{Time: time.Now()}
+	}
+	s.events = append(s.events, e)
+	return e.EventID
+}
+
+// listEvents returns the matching events, most recent first.
+func (s *Server) listEvents(r *http.Request) result {
+	q := r.URL.Query()
+	events := []*samplify.Event{}
+	for i := len(s.events) - 1; i >= 0; i-- {
+		e := s.events[i]
+		if matchesQuery(q, "extProjectId", e.ExtProjectID) && matchesQuery(q, "extLineItemId", e.ExtLineItemID) &&
+			matchesQuery(q, "eventType", string(e.EventType)) && matchesQuery(q, "type", string(e.EventType)) {
+			events = append(events, e)
+		}
+	}
+	start, end, m := paginate(r, len(events))
+	return result{data: events[start:end], meta: m}
+}
+
+func (s *Server) findEvent(id string) *samplify.Event {
+	n, _ := strconv.ParseInt(id, 10, 64)
+	for _, e := range s.events {
+		if e.EventID == n {
+			return e
+		}
+	}
+	return nil
+}
+
+func (s *Server) handleEvents(r *http.Request, p []string) result {
+	if len(p) == 0 {
+		if r.Method == http.MethodGet {
+			return s.listEvents(r)
+		}
+		return notFound(r)
+	}
+	e := s.findEvent(p[0])
+	if e == nil {
+		return result{err: errorf(http.StatusNotFound, "EVENT_NOT_FOUND", "event %s not found", p[0])}
+	}
+	switch {
+	case len(p) == 1 && r.Method == http.MethodGet:
+		return result{data: e}
+	case len(p) == 2 && r.Method == http.MethodPost && (p[1] == "accept" || p[1] == "reject"):
+		if e.Actions == nil {
+			return result{err: errorf(http.StatusConflict, "EVENT_ACTION_NOT_APPLICABLE", "event %d has no actions", e.EventID)}
+		}
+		eventType := samplify.EventLineItemRepriceAccepted
+		if p[1] == "reject" {
+			eventType = samplify.EventLineItemRepriceRejected
+		}
+		e.Actions = nil
+		parent := e.EventID
+		s.recordEvent(&samplify.Event{
+			EventType:     eventType,
+			ExtProjectID:  e.ExtProjectID,
+			ExtLineItemID: e.ExtLineItemID,
+			ParentEventID: &parent,
+		})
+		return result{data: nil}
+	}
+	return notFound(r)
+}
+
+func (s *Server) handleTemplates(r *http.Request, p []string, body []byte) result {
+	switch {
+	case len(p) == 0 && r.Method == http.MethodPost:
+		t := &samplify.TemplateData{}
+		if err := json.Unmarshal(body, t); err != nil {
+			return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "%v", err)}
+		}
+		s.nextTemplateID++
+		now := time.Now().UTC().Format(time.RFC3339)
+		t.ID, t.Editable, t.State, t.CreatedAt, t.UpdatedAt = s.nextTemplateID, true, "ACTIVE", &now, &now
+		s.templates[t.ID] = t
+		s.templateIDs = append(s.templateIDs, t.ID)
+		return result{data: t}
+	case len(p) == 1:
+		id, _ := strconv.Atoi(p[0])
+		t, ok := s.templates[id]
+		if !ok {
+			return result{err: errorf(http.StatusNotFound, "TEMPLATE_NOT_FOUND", "template %s not found", p[0])}
+		}
+		switch r.Method {
+		case http.MethodPost:
+			created := t.CreatedAt
+			if err := json.Unmarshal(body, t); err != nil {
+				return result{err: errorf(http.StatusBadRequest, "BAD_REQUEST", "%v", err)}
+			}
+			now := time.Now().UTC().Format(time.RFC3339)
+			t.ID, t.CreatedAt, t.UpdatedAt = id, created, &now
+			return result{data: t}
+		case http.MethodDelete:
+			delete(s.templates, id)
+			for i, tid := range s.templateIDs {
+				if tid == id {
+					s.templateIDs = append(s.templateIDs[:i], s.templateIDs[i+1:]...)
+					break
+				}
+			}
+			return result{data: nil}
+		}
+	case len(p) == 2 && r.Method == http.MethodGet:
+		list := []*samplify.TemplateData{}
+		for _, id := range s.templateIDs {
+			t := s.templates[id]
+			if t.CountryISOCode != nil && *t.CountryISOCode == p[0] && t.LanguageISOCode != nil && *t.LanguageISOCode == p[1] {
+				list = append(list, t)
+			}
+		}
+		start, end, m := paginate(r, len(list))
+		return result{data: list[start:end], meta: m}
+	}
+	return notFound(r)
+}
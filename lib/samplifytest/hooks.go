@@ -0,0 +1,102 @@
+package samplifytest
+
+import (
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// Hook intercepts the requests of a Server before they are handled. It returns
+// true if it wrote a response, in which case the request is not handled any
+// further.
+type Hook func(w http.ResponseWriter, r *http.Request) bool
+
+// AddHook adds h to the hooks of the server. Hooks run in the order they were
+// added.
+func (s *Server) AddHook(h Hook) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = append(s.hooks, h)
+}
+
+// ClearHooks removes all hooks of the server.
+func (s *Server) ClearHooks() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks = nil
+}
+
+// matches reports whether r has the given method and its path, relative to
+// the prefix of its service, matches pattern as in path.Match. An empty method
+// matches any method.
+func matches(r *http.Request, method, pattern string) bool {
+	if len(method) > 0 && r.Method != method {
+		return false
+	}
+	ok, _ := path.Match(pattern, splitRoute(r).path)
+	return ok
+}
+
+// counter limits a hook to a number of requests, or none if times is not
+// positive.
+type counter struct {
+	mu    sync.Mutex
+	times int
+	seen  int
+}
+
+func (c *counter) take() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.times > 0 && c.seen >= c.times {
+		return false
+	}
+	c.seen++
+	return true
+}
+
+// InjectError makes the next times requests matching method and pattern fail
+// with status, or every matching request if times is not positive. The
+// pattern is matched against the path relative to the prefix of the service,
+// e.g. "/projects/*/buy", as in path.Match.
+func (s *Server) InjectError(method, pattern string, status, times int) {
+	c := &counter{times: times}
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if !matches(r, method, pattern) || !c.take() {
+			return false
+		}
+		writeError(w, status, "INJECTED", http.StatusText(status))
+		return true
+	})
+}
+
+// InjectLatency delays the requests matching method and pattern by d, or
+// until they are cancelled.
+func (s *Server) InjectLatency(method, pattern string, d time.Duration) {
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if !matches(r, method, pattern) {
+			return false
+		}
+		t := time.NewTimer(d)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+		}
+		return false
+	})
+}
+
+// RejectTokens makes the next times requests to the API fail with 401
+// Unauthorized, whatever their access token, as if it had expired.
+func (s *Server) RejectTokens(times int) {
+	c := &counter{times: times}
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if splitRoute(r).prefix != APIPrefix || !c.take() {
+			return false
+		}
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "access token rejected")
+		return true
+	})
+}
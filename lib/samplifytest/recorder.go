@@ -0,0 +1,359 @@
+package samplifytest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNoRecording ... Returned by a Recorder in ModeReplay for requests that
+// match no recorded interaction
+var ErrNoRecording = errors.New("no recorded interaction matches the request")
+
+// Redacted replaces the values that a Recorder scrubs from its cassette.
+const Redacted = "REDACTED"
+
+// Mode determines whether a Recorder sends requests or replays them.
+type Mode int
+
+// Mode values
+const (
+	// ModeReplay replays recorded interactions and never sends requests.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request and records it, replacing the
+	// interactions recorded before.
+	ModeRecord
+	// ModeReplayOrRecord replays the recorded interactions and sends and
+	// records the requests that match none of them.
+	ModeReplayOrRecord
+)
+
+// DefaultScrubFields are the JSON fields whose values a Recorder scrubs from
+// request and response bodies: the password of a TokenRequest, the tokens of
+// a TokenResponse and the security keys of EndLinks and sales orders.
+var DefaultScrubFields = []string{
+	"password", "accessToken", "refreshToken",
+	"securityKey1", "securityKey2", "basicSecurityKey", "highSecurityKey",
+}
+
+// DefaultScrubHeaders are the headers a Recorder removes from the requests
+// and responses it records.
+var DefaultScrubHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// RecorderOptions configures a Recorder.
+type RecorderOptions struct {
+	Mode Mode
+	// Transport sends the requests that are recorded. If nil,
+	// http.DefaultTransport is used.
+	Transport http.RoundTripper
+	// ScrubFields are JSON fields scrubbed in addition to
+	// DefaultScrubFields, at any depth of a body.
+	ScrubFields []string
+	// ScrubHeaders are headers removed in addition to DefaultScrubHeaders.
+	ScrubHeaders []string
+}
+
+// Cassette is the file format of a Recorder.
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest ...
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// RecordedResponse ...
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Recorder is an http.RoundTripper that records the requests of a client and
+// their responses to a cassette file, and replays them without network
+// access. Set it as the Transport of samplify.ClientOptions.
+//
+// Requests match a recorded interaction on their method, path, query and,
+// for JSON bodies, their body after scrubbing, regardless of the host and of
+// the order of query parameters and JSON fields. Each interaction is replayed
+// once, in the order they were recorded, so that repeated requests, such as
+// polls for feasibility, replay their successive responses.
+//
+// Authorization headers and the values of the fields in DefaultScrubFields
+// are scrubbed before interactions are recorded, so that cassettes can be
+// committed. A Recorder is safe for concurrent use.
+type Recorder struct {
+	path         string
+	mode         Mode
+	transport    http.RoundTripper
+	scrubFields  map[string]bool
+	scrubHeaders []string
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+	recorded bool
+}
+
+// NewRecorder returns a Recorder for the cassette at path. The cassette must
+// exist in ModeReplay.
+func NewRecorder(path string, opts *RecorderOptions) (*Recorder, error) {
+	if opts == nil {
+		opts = &RecorderOptions{}
+	}
+	r := &Recorder{
+		path:         path,
+		mode:         opts.Mode,
+		transport:    opts.Transport,
+		scrubFields:  map[string]bool{},
+		scrubHeaders: append(append([]string{}, DefaultScrubHeaders...), opts.ScrubHeaders...),
+		cassette:     &Cassette{},
+	}
+	if r.transport == nil {
+		r.transport = http.DefaultTransport
+	}
+	for _, f := range append(append([]string{}, DefaultScrubFields...), opts.ScrubFields...) {
+		r.scrubFields[f] = true
+	}
+	if r.mode != ModeRecord {
+		b, err := ioutil.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(b, r.cassette); err != nil {
+				return nil, fmt.Errorf("cassette %s: %w", path, err)
+			}
+		case !os.IsNotExist(err) || r.mode == ModeReplay:
+			return nil, err
+		}
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+	return r, nil
+}
+
+// RoundTrip replays the response recorded for req, or sends and records req,
+// depending on the mode of the recorder.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+	recorded := r.newRecordedRequest(req, body)
+	if r.mode != ModeRecord {
+		if i := r.replay(recorded); i != nil {
+			return i.Response.httpResponse(req), nil
+		}
+		if r.mode == ModeReplay {
+			return nil, fmt.Errorf("%w: %s %s", ErrNoRecording, req.Method, recorded.URL)
+		}
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, &Interaction{
+		Request: recorded,
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     r.scrubHeader(resp.Header),
+			Body:       r.scrubBody(respBody),
+		},
+	})
+	r.used = append(r.used, true)
+	r.recorded = true
+	return resp, nil
+}
+
+// Save writes the cassette to its file if any interaction was recorded.
+func (r *Recorder) Save() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.recorded {
+		return nil
+	}
+	b, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(r.path, append(b, '\n'), 0644)
+}
+
+// Unused returns the recorded interactions that were not replayed.
+func (r *Recorder) Unused() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []*Interaction
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// replay returns the first interaction that matches req and was not replayed
+// yet, and marks it as replayed.
+func (r *Recorder) replay(req RecordedRequest) *Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, in := range r.cassette.Interactions {
+		if !r.used[i] && matchRequest(in.Request, req) {
+			r.used[i] = true
+			return in
+		}
+	}
+	return nil
+}
+
+func (r *Recorder) newRecordedRequest(req *http.Request, body []byte) RecordedRequest {
+	u := *req.URL
+	u.User = nil
+	return RecordedRequest{
+		Method: req.Method,
+		URL:    u.String(),
+		Header: r.scrubHeader(req.Header),
+		Body:   r.scrubBody(body),
+	}
+}
+
+// matchRequest reports whether req matches the recorded request.
+func matchRequest(recorded, req RecordedRequest) bool {
+	if recorded.Method != req.Method {
+		return false
+	}
+	ru, err1 := url.Parse(recorded.URL)
+	u, err2 := url.Parse(req.URL)
+	if err1 != nil || err2 != nil || ru.Path != u.Path || ru.Query().Encode() != u.Query().Encode() {
+		return false
+	}
+	rb, ok1 := normalizeJSON(recorded.Body)
+	b, ok2 := normalizeJSON(req.Body)
+	// Bodies that are not JSON, such as multipart uploads with random
+	// boundaries, are not compared.
+	return !ok1 || !ok2 || rb == b
+}
+
+// normalizeJSON returns body with its object keys sorted, and false if it is
+// not JSON. An empty body is treated as null.
+func normalizeJSON(body string) (string, bool) {
+	if len(strings.TrimSpace(body)) == 0 {
+		return "null", true
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(body), &v); err != nil {
+		return "", false
+	}
+	b, _ := json.Marshal(v)
+	return string(b), true
+}
+
+func (r *Recorder) scrubHeader(h http.Header) http.Header {
+	h = h.Clone()
+	for _, k := range r.scrubHeaders {
+		h.Del(k)
+	}
+	if len(h) == 0 {
+		return nil
+	}
+	return h
+}
+
+// scrubBody replaces the values of the scrubbed fields of a JSON body with
+// Redacted. Other bodies are returned unchanged.
+func (r *Recorder) scrubBody(body []byte) string {
+	var v interface{}
+	if len(body) == 0 || json.Unmarshal(body, &v) != nil {
+		return string(body)
+	}
+	if !r.scrub(v) {
+		return string(body)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+// scrub replaces the scrubbed fields in v and reports whether it changed any.
+func (r *Recorder) scrub(v interface{}) bool {
+	changed := false
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, val := range v {
+			if r.scrubFields[k] {
+				if s, ok := val.(string); !ok || len(s) > 0 {
+					v[k] = Redacted
+					changed = true
+				}
+				continue
+			}
+			changed = r.scrub(val) || changed
+		}
+	case []interface{}:
+		for _, val := range v {
+			changed = r.scrub(val) || changed
+		}
+	}
+	return changed
+}
+
+// readBody returns the body of req, leaving req able to be sent.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody == nil {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		return body, err
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func (rr RecordedResponse) httpResponse(req *http.Request) *http.Response {
+	header := rr.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(strings.NewReader(rr.Body)),
+		ContentLength: int64(len(rr.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,79 @@
+package samplifytest_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+	"github.com/researchnow/go-samplifyapi-client/lib/samplifytest"
+)
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "samplifytest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassette.json")
+	s := samplifytest.NewServer()
+	s.SetCredentials("client", "user", "s3cret")
+	flow := func(client *samplify.Client) []samplify.FeasibilityStatus {
+		if _, err := client.CreateProject(projectCriteria("p1")); err != nil {
+			t.Fatal(err)
+		}
+		var statuses []samplify.FeasibilityStatus
+		for i := 0; i < 2; i++ {
+			res, err := client.GetFeasibility("p1", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			statuses = append(statuses, res.List[0].Feasibility.Status)
+		}
+		return statuses
+	}
+
+	rec, err := samplifytest.NewRecorder(path, &samplifytest.RecorderOptions{Mode: samplifytest.ModeRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := s.ClientOptions()
+	options.Transport = rec
+	recorded := flow(samplify.NewClient("client", "user", "s3cret", options))
+	if err := rec.Save(); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s3cret", "Bearer", "access-1", "refresh-1"} {
+		if strings.Contains(string(b), secret) {
+			t.Errorf("expected %q to be scrubbed from the cassette", secret)
+		}
+	}
+
+	rec, err = samplifytest.NewRecorder(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options.Transport = rec
+	replayed := flow(samplify.NewClient("client", "user", "s3cret", options))
+	if len(replayed) != 2 || replayed[0] != recorded[0] || replayed[1] != recorded[1] ||
+		replayed[1] != samplify.FeasibilityStatusReady {
+		t.Errorf("expected the replay to return %v, got %v", recorded, replayed)
+	}
+	if unused := rec.Unused(); len(unused) != 0 {
+		t.Errorf("expected all interactions to be replayed, got %d unused", len(unused))
+	}
+
+	client := samplify.NewClient("client", "user", "s3cret", options)
+	if _, err := client.GetProjectBy("p2"); !errors.Is(err, samplifytest.ErrNoRecording) {
+		t.Errorf("expected ErrNoRecording, got %v", err)
+	}
+}
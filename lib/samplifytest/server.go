@@ -0,0 +1,317 @@
+// Package samplifytest provides an in-memory fake of the Samplify Demand API,
+// for testing code that uses a samplify.Client without the real service.
+//
+// The fake keeps projects, line items, events, templates and permissions in
+// memory, issues and checks tokens, and applies the line item transitions of
+// the client.
+// Hooks can inject errors, latency and rejected tokens.
+package samplifytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+// Credentials accepted by a new Server, as used by Server.NewClient.
+const (
+	ClientID = "samplifytest"
+	Username = "user@samplifytest.com"
+	Password = "password"
+)
+
+// Path prefixes of the services served by a Server.
+const (
+	AuthPrefix     = "/auth/v1"
+	APIPrefix      = "/sample/v1"
+	InternalPrefix = "/internal/v1"
+	StatusPrefix   = "/status"
+)
+
+// Default token lifetimes of a new Server.
+const (
+	DefaultAccessTokenLifetime  = 30 * time.Minute
+	DefaultRefreshTokenLifetime = 2 * time.Hour
+)
+
+// Server is an in-memory fake of the Demand API running on an
+// httptest.Server. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu              sync.Mutex
+	credentials     samplify.TokenRequest
+	accessLifetime  time.Duration
+	refreshLifetime time.Duration
+	accessTokens    map[string]time.Time
+	refreshTokens   map[string]time.Time
+	nextToken       int
+	hooks           []Hook
+	requests        []string
+
+	projects         map[string]*samplify.Project
+	projectIDs       []string
+	permissions      map[string]*samplify.ProjectPermissions
+	feasibilityPolls int
+	polls            map[string]int
+	events           []*samplify.Event
+	nextEventID      int64
+	templates        map[int]*samplify.TemplateData
+	templateIDs      []int
+	nextTemplateID   int
+}
+
+// NewServer starts and returns a new Server. It must be closed with Close
+// when no longer needed.
+func NewServer() *Server {
+	s := &Server{
+		credentials:      samplify.TokenRequest{ClientID: ClientID, Username: Username, Password: Password},
+		accessLifetime:   DefaultAccessTokenLifetime,
+		refreshLifetime:  DefaultRefreshTokenLifetime,
+		accessTokens:     map[string]time.Time{},
+		refreshTokens:    map[string]time.Time{},
+		projects:         map[string]*samplify.Project{},
+		permissions:      map[string]*samplify.ProjectPermissions{},
+		feasibilityPolls: 1,
+		polls:            map[string]int{},
+		templates:        map[int]*samplify.TemplateData{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// ClientOptions returns options that point a client at the server.
+func (s *Server) ClientOptions() *samplify.ClientOptions {
+	timeout := 5
+	return &samplify.ClientOptions{
+		APIBaseURL:  s.URL + APIPrefix,
+		AuthURL:     s.URL + AuthPrefix,
+		InternalURL: s.URL + InternalPrefix,
+		StatusURL:   s.URL + StatusPrefix,
+		GatewayURL:  s.URL + StatusPrefix + "/gateway",
+		Timeout:     &timeout,
+	}
+}
+
+// NewClient returns a client that uses the server with the credentials it
+// accepts.
+func (s *Server) NewClient() *samplify.Client {
+	s.mu.Lock()
+	c := s.credentials
+	s.mu.Unlock()
+	return samplify.NewClient(c.ClientID, c.Username, c.Password, s.ClientOptions())
+}
+
+// SetCredentials sets the credentials the server accepts for logins.
+func (s *Server) SetCredentials(clientID, username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.credentials = samplify.TokenRequest{ClientID: clientID, Username: username, Password: password}
+}
+
+// SetTokenLifetime sets the lifetime of the tokens issued from now on.
+func (s *Server) SetTokenLifetime(access, refresh time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessLifetime = access
+	s.refreshLifetime = refresh
+}
+
+// ExpireTokens invalidates every access token issued so far, so that clients
+// have to refresh them. Refresh tokens stay valid.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokens = map[string]time.Time{}
+}
+
+// Requests returns the requests handled so far, as "METHOD /path" with the
+// path relative to the prefix of its service.
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+// route is a request split into its service prefix and the path below it.
+type route struct {
+	prefix string
+	path   string
+	parts  []string
+}
+
+func splitRoute(r *http.Request) route {
+	for _, prefix := range []string{AuthPrefix, APIPrefix, InternalPrefix, StatusPrefix} {
+		if strings.HasPrefix(r.URL.Path, prefix+"/") || r.URL.Path == prefix {
+			p := strings.TrimPrefix(r.URL.Path, prefix)
+			return route{prefix: prefix, path: p, parts: strings.Split(strings.Trim(p, "/"), "/")}
+		}
+	}
+	return route{path: r.URL.Path}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	rt := splitRoute(r)
+	s.mu.Lock()
+	s.requests = append(s.requests, r.Method+" "+rt.path)
+	hooks := append([]Hook(nil), s.hooks...)
+	s.mu.Unlock()
+
+	for _, h := range hooks {
+		if h(w, r) {
+			return
+		}
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+		return
+	}
+	switch rt.prefix {
+	case AuthPrefix:
+		s.serveAuth(w, r, rt, body)
+	case APIPrefix:
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired access token")
+			return
+		}
+		s.serveAPI(w, r, rt, body)
+	case StatusPrefix:
+		writeData(w, http.StatusOK, map[string]string{"status": "UP"}, nil)
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("no route for %s", r.URL.Path))
+	}
+}
+
+// authorized reports whether r carries a valid access token.
+func (s *Server) authorized(r *http.Request) bool {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiry, ok := s.accessTokens[token]
+	return ok && time.Now().Before(expiry)
+}
+
+func (s *Server) serveAuth(w http.ResponseWriter, r *http.Request, rt route, body []byte) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", r.Method)
+		return
+	}
+	switch rt.path {
+	case "/token/password":
+		var req samplify.TokenRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "BAD_REQUEST", err.Error())
+			return
+		}
+		s.mu.Lock()
+		ok := req == s.credentials
+		s.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "invalid credentials")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.issueTokens(""))
+	case "/token/refresh":
+		var req struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		json.Unmarshal(body, &req)
+		s.mu.Lock()
+		expiry, ok := s.refreshTokens[req.RefreshToken]
+		s.mu.Unlock()
+		if !ok || time.Now().After(expiry) {
+			writeError(w, http.StatusUnauthorized, "INVALID_REFRESH_TOKEN", "invalid or expired refresh token")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.issueTokens(req.RefreshToken))
+	case "/logout":
+		var req struct {
+			AccessToken  string `json:"accessToken"`
+			RefreshToken string `json:"refreshToken"`
+		}
+		json.Unmarshal(body, &req)
+		s.mu.Lock()
+		delete(s.accessTokens, req.AccessToken)
+		delete(s.refreshTokens, req.RefreshToken)
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	case "/switchCompany":
+		if !s.authorized(r) {
+			writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "invalid or expired access token")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.issueTokens(""))
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("no route for %s", r.URL.Path))
+	}
+}
+
+// issueTokens issues a new access token, and a new refresh token unless
+// refreshToken is set.
+func (s *Server) issueTokens(refreshToken string) *samplify.TokenResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.nextToken++
+	access := fmt.Sprintf("access-%d", s.nextToken)
+	s.accessTokens[access] = now.Add(s.accessLifetime)
+	if len(refreshToken) == 0 {
+		refreshToken = fmt.Sprintf("refresh-%d", s.nextToken)
+		s.refreshTokens[refreshToken] = now.Add(s.refreshLifetime)
+	}
+	return &samplify.TokenResponse{
+		AccessToken:      access,
+		ExpiresIn:        uint(s.accessLifetime / time.Second),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: uint(time.Until(s.refreshTokens[refreshToken]) / time.Second),
+	}
+}
+
+// meta describes a page of a list response.
+type meta struct {
+	Links    map[string]string `json:"links"`
+	Total    int               `json:"total"`
+	PageSize int               `json:"pageSize"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL_ERROR", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(b)
+}
+
+// writeData writes data in the envelope of the API's responses.
+func writeData(w http.ResponseWriter, status int, data interface{}, m *meta) {
+	res := map[string]interface{}{
+		"data":   data,
+		"status": samplify.ResponseStatus{Message: "success", Errors: []samplify.ErrorInfo{}},
+	}
+	if m != nil {
+		res["meta"] = m
+	}
+	writeJSON(w, status, res)
+}
+
+// writeError writes an error response with a single API error.
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"data": nil,
+		"status": samplify.ResponseStatus{
+			Message: "fail",
+			Errors:  []samplify.ErrorInfo{{Code: code, Message: message}},
+		},
+	})
+}
@@ -0,0 +1,223 @@
+package samplifytest_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+	"github.com/researchnow/go-samplifyapi-client/lib/samplifytest"
+)
+
+func projectCriteria(id string) *samplify.CreateProjectCriteria {
+	surveyURL := "www.mysurvey.com/live/survey"
+	return &samplify.CreateProjectCriteria{
+		ExtProjectID:       id,
+		Title:              "Test Survey",
+		NotificationEmails: []string{"api-test@researchnow.com"},
+		Category:           &samplify.Category{SurveyTopic: []string{"AUTOMOTIVE"}},
+		LineItems: []*samplify.CreateLineItemCriteria{{
+			ExtLineItemID:       "l1",
+			Title:               "US College",
+			CountryISOCode:      "US",
+			LanguageISOCode:     "en",
+			SurveyURL:           &surveyURL,
+			IndicativeIncidence: 20,
+			LengthOfInterview:   10,
+			RequiredCompletes:   200,
+		}},
+	}
+}
+
+func TestProjectLifecycle(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+
+	if _, err := client.CreateProject(projectCriteria("p1")); err != nil {
+		t.Fatal(err)
+	}
+	_, err := client.LaunchLineItem("p1", "l1")
+	if !errors.Is(err, samplify.ErrInvalidTransition) {
+		t.Errorf("expected a provisioned line item not to launch, got %v", err)
+	}
+	client.Options.SkipStateCheck = true
+	_, err = client.LaunchLineItem("p1", "l1")
+	client.Options.SkipStateCheck = false
+	if !errors.Is(err, samplify.ErrConflict) {
+		t.Errorf("expected the server to refuse the launch, got %v", err)
+	}
+
+	res, err := client.BuyProject("p1", []*samplify.BuyProjectCriteria{{
+		ExtLineItemID: "l1",
+		SurveyURL:     "www.mysurvey.com/live/survey?rid=<#IdParameter[Value]>",
+		SurveyTestURL: "www.mysurvey.com/test/survey",
+	}})
+	if err != nil || len(res.List) != 1 || res.List[0].State != samplify.StateAwaitingApproval {
+		t.Fatalf("expected the line item to await approval, got %+v, %v", res, err)
+	}
+	if _, err := client.LaunchLineItem("p1", "l1"); !errors.Is(err, samplify.ErrInvalidTransition) {
+		t.Errorf("expected a line item awaiting approval not to launch, got %v", err)
+	}
+	if err := s.SetLineItemState("p1", "l1", samplify.StateLaunched); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.PauseLineItem("p1", "l1"); err != nil {
+		t.Fatal(err)
+	}
+	launched, err := client.LaunchLineItem("p1", "l1")
+	if err != nil || launched.LineItem.State != samplify.StateLaunched {
+		t.Fatalf("expected the line item to launch, got %+v, %v", launched, err)
+	}
+	if p, _ := s.Project("p1"); p.State != samplify.StateLaunched {
+		t.Errorf("expected the project to be launched, got %s", p.State)
+	}
+
+	closed, err := client.CloseProject("p1")
+	if err != nil || closed.Project.State != samplify.StateClosed || closed.Project.LineItems[0].State != samplify.StateClosed {
+		t.Fatalf("expected the project to close, got %+v, %v", closed, err)
+	}
+
+	events, err := client.GetEvents(samplify.NewQuery().WithExtLineItemID("l1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []samplify.EventStatus
+	for _, e := range events.List {
+		states = append(states, e.Resource.Status.NewValue)
+	}
+	if len(states) != 5 || states[0] != samplify.EventStatusClosed || states[1] != samplify.EventStatusLaunched {
+		t.Errorf("expected 5 state changes, most recent first, got %v", states)
+	}
+}
+
+func TestNullLineItems(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+	if _, err := client.CreateProject(projectCriteria("p1")); err != nil {
+		t.Fatal(err)
+	}
+	token := client.CurrentAuth().AccessToken
+
+	requests := []struct{ method, path, body string }{
+		{http.MethodPost, "/projects", `{"extProjectId":"p2","lineItems":[null]}`},
+		{http.MethodPost, "/projects/p1", `{"lineItems":[null]}`},
+		{http.MethodPost, "/projects/p1/buy", `[null]`},
+		{http.MethodPost, "/projects/p1/permissions", `{"users":[null],"teams":[null]}`},
+	}
+	for _, r := range requests {
+		req, _ := http.NewRequest(r.method, s.URL+samplifytest.APIPrefix+r.path, strings.NewReader(r.body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", r.method, r.path, err)
+		}
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			t.Errorf("%s %s: expected 200, got %d", r.method, r.path, res.StatusCode)
+		}
+	}
+	if p, ok := s.Project("p2"); !ok || len(p.LineItems) != 0 {
+		t.Errorf("expected p2 without line items, got %+v", p)
+	}
+	// The server still serves requests.
+	if _, err := client.GetProjectBy("p1"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFeasibility(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	s.SetFeasibilityPolls(2)
+	client := s.NewClient()
+	if _, err := client.CreateProject(projectCriteria("p1")); err != nil {
+		t.Fatal(err)
+	}
+
+	updates := 0
+	res, err := client.WaitForFeasibility(context.Background(), "p1", &samplify.WaitForFeasibilityOptions{
+		Interval: time.Millisecond,
+		OnUpdate: func(*samplify.GetFeasibilityResponse) { updates++ },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updates != 3 || res.List[0].Feasibility.Status != samplify.FeasibilityStatusReady || !res.List[0].Feasibility.Feasible {
+		t.Errorf("expected feasibility to be ready after 3 polls, got %d polls and %+v", updates, res.List[0].Feasibility)
+	}
+}
+
+func TestHooks(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+	if _, err := client.CreateProject(projectCriteria("p1")); err != nil {
+		t.Fatal(err)
+	}
+
+	s.RejectTokens(1)
+	if _, err := client.GetProjectBy("p1"); err != nil {
+		t.Errorf("expected the client to renew its token, got %v", err)
+	}
+	s.ExpireTokens()
+	if _, err := client.GetProjectBy("p1"); err != nil {
+		t.Errorf("expected the client to refresh its token, got %v", err)
+	}
+
+	s.InjectError(http.MethodGet, "/projects/*", http.StatusServiceUnavailable, 1)
+	if _, err := client.GetProjectBy("p1"); !errors.Is(err, samplify.ErrServer) {
+		t.Errorf("expected ErrServer, got %v", err)
+	}
+	if _, err := client.GetProjectBy("p1"); err != nil {
+		t.Errorf("expected the error to be injected once, got %v", err)
+	}
+
+	s.InjectLatency("", "/projects/*/report", 200*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetProjectReportWithContext(ctx, "p1"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to time out, got %v", err)
+	}
+
+	s.ClearHooks()
+	if _, err := client.GetProjectReport("p1"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTemplatesAndPermissions(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+	if _, err := client.CreateProject(projectCriteria("p1")); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := client.CreateTemplate(&samplify.TemplateCriteria{
+		Name: "Gender", CountryISOCode: "US", LanguageISOCode: "en", QuotaPlan: &samplify.QuotaPlan{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := client.GetTemplateList("US", "en", nil)
+	if err != nil || len(list.Data) != 1 || list.Data[0].ID != created.Data.ID {
+		t.Fatalf("expected the created template, got %+v, %v", list, err)
+	}
+	if _, err := client.DeleteTemplate(created.Data.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.UpdateTemplate(created.Data.ID, &samplify.TemplateCriteria{Name: "Age"}); !errors.Is(err, samplify.ErrNotFound) {
+		t.Errorf("expected a deleted template not to be found, got %v", err)
+	}
+
+	users := []*samplify.UserPermission{{ID: 7, Role: "VIEWER"}}
+	perms, err := client.UpsertProjectPermissions(&samplify.UpsertPermissionsCriteria{ExtProjectID: "p1", UserPermissions: &users})
+	if err != nil || len(perms.ProjectPermissions.Users) != 1 || perms.ProjectPermissions.Users[0].Role != "VIEWER" {
+		t.Errorf("expected the user permission to be stored, got %+v, %v", perms, err)
+	}
+}
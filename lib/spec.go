@@ -0,0 +1,471 @@
+package samplify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Spec errors, matched with errors.Is
+var (
+	ErrInvalidSpecState      = errors.New("state of a line item spec must be LAUNCHED or PAUSED")
+	ErrLineItemNotUpdateable = errors.New("line item cannot be updated in its current state")
+	ErrLineItemFinished      = errors.New("line item has finished and is not changed anymore")
+	ErrLineItemNotInField    = errors.New("line item is neither launched nor paused")
+)
+
+// finalStates are the states of line items that have finished fielding. They
+// are neither updated, launched nor paused again, so a spec that differs
+// from them is not applied to them.
+var finalStates = map[State]bool{
+	StateCompleted: true,
+	StateClosed:    true,
+	StateCancelled: true,
+	StateInvoiced:  true,
+}
+
+// ProjectSpec is the desired state of a project, as kept in a spec file: the
+// fields of CreateProjectCriteria, with line items that may also have a
+// desired state. See LoadSpec and Client.PlanProject.
+type ProjectSpec struct {
+	CreateProjectCriteria
+	LineItems []*LineItemSpec `json:"lineItems"`
+}
+
+// LineItemSpec is the desired state of a line item of a ProjectSpec.
+type LineItemSpec struct {
+	CreateLineItemCriteria
+	// State is LAUNCHED or PAUSED to launch or pause the line item once it
+	// has been bought, or empty to leave its state unchanged.
+	State State `json:"state,omitempty"`
+}
+
+// LoadSpec reads the project spec at path. Files with a .json extension are
+// decoded as JSON, all others as YAML, both with the JSON field names of
+// CreateProjectCriteria. Unknown keys are rejected so that typos do not go
+// unnoticed.
+func LoadSpec(path string) (*ProjectSpec, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".json") {
+		b, err = yamlToJSON(b)
+	}
+	s := &ProjectSpec{}
+	if err == nil {
+		d := json.NewDecoder(bytes.NewReader(b))
+		d.DisallowUnknownFields()
+		err = d.Decode(s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// yamlToJSON converts a YAML document to JSON, so that it is decoded with the
+// JSON field names.
+func yamlToJSON(b []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.UnmarshalStrict(b, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonValue(v))
+}
+
+// jsonValue replaces the maps decoded by yaml, which have interface{} keys,
+// with maps that can be encoded as JSON.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range v {
+			v[i] = jsonValue(e)
+		}
+	}
+	return v
+}
+
+// Criteria returns the CreateProjectCriteria of the project with all its line
+// items.
+func (s *ProjectSpec) Criteria() *CreateProjectCriteria {
+	c := s.CreateProjectCriteria
+	c.LineItems = []*CreateLineItemCriteria{}
+	for _, li := range nonNil(s.LineItems) {
+		c.LineItems = append(c.LineItems, &li.CreateLineItemCriteria)
+	}
+	return &c
+}
+
+// Validate checks the spec as CreateProject would, along with the desired
+// states of the line items.
+func (s *ProjectSpec) Validate() error {
+	c := s.Criteria()
+	if err := Validate(c); err != nil {
+		return err
+	}
+	for _, li := range nonNil(s.LineItems) {
+		if err := ValidateSchedule(&li.DaysInField, li.FieldSchedule); err != nil {
+			return fmt.Errorf("line item %s: %w", li.ExtLineItemID, err)
+		}
+		if li.State != "" && li.State != StateLaunched && li.State != StatePaused {
+			return fmt.Errorf("line item %s: %w, not %s", li.ExtLineItemID, ErrInvalidSpecState, li.State)
+		}
+	}
+	return nil
+}
+
+// updateCriteria returns an update setting every field of the spec that line
+// items return, to be compared with DiffLineItem. SurveyTestURLParams and
+// CostPerInterview are left out.
+func (s *LineItemSpec) updateCriteria() *UpdateLineItemCriteria {
+	c := &s.CreateLineItemCriteria
+	u := &UpdateLineItemCriteria{
+		ExtLineItemID:       c.ExtLineItemID,
+		Title:               &c.Title,
+		CountryISOCode:      &c.CountryISOCode,
+		LanguageISOCode:     &c.LanguageISOCode,
+		SurveyURL:           c.SurveyURL,
+		SurveyTestURL:       c.SurveyTestURL,
+		IndicativeIncidence: &c.IndicativeIncidence,
+		FieldSchedule:       c.FieldSchedule,
+		LengthOfInterview:   &c.LengthOfInterview,
+		DeliveryType:        c.DeliveryType,
+		RequiredCompletes:   &c.RequiredCompletes,
+		QuotaPlan:           c.QuotaPlan,
+		SurveyURLParams:     c.SurveyURLParams,
+		Targets:             c.Targets,
+		SurveyTestingNotes:  c.SurveyTestingNotes,
+	}
+	if c.FieldSchedule == nil {
+		u.DaysInField = &c.DaysInField
+	}
+	if c.Sources != nil {
+		u.Sources = &c.Sources
+	}
+	return u
+}
+
+// StepType ...
+type StepType string
+
+// StepType values
+const (
+	StepCreateProject  StepType = "create project"
+	StepUpdateProject  StepType = "update project"
+	StepAddLineItem    StepType = "add line item"
+	StepUpdateLineItem StepType = "update line item"
+	StepLaunchLineItem StepType = "launch line item"
+	StepPauseLineItem  StepType = "pause line item"
+)
+
+// PlanStep is a change made by a Plan.
+type PlanStep struct {
+	Type          StepType
+	ExtLineItemID string
+	// Project is the project to create, with its line items.
+	Project *CreateProjectCriteria
+	// ProjectUpdate is the update of the project, and ProjectChanges the
+	// changes it makes.
+	ProjectUpdate  *UpdateProjectCriteria
+	ProjectChanges Changes
+	// LineItem is the line item to add.
+	LineItem *CreateLineItemCriteria
+	// Diff holds the changes of the line item to update.
+	Diff *LineItemDiff
+	// Err, if not nil, is why the step cannot be applied: the line item
+	// differs from the spec but is not updateable (ErrLineItemNotUpdateable).
+	Err error
+	// Skip, if not nil, is why the step is left out when the plan is
+	// applied: the line item has finished (ErrLineItemFinished), or its
+	// state cannot be changed to the desired one yet, such as while it
+	// awaits approval (ErrLineItemNotInField). Unlike Err, it does not
+	// prevent the other steps.
+	Skip error
+}
+
+// String returns the type of the step and the project or line item it
+// changes.
+func (s *PlanStep) String() string {
+	switch s.Type {
+	case StepCreateProject:
+		return fmt.Sprintf("%s %s", s.Type, s.Project.ExtProjectID)
+	case StepUpdateProject:
+		return fmt.Sprintf("%s %s", s.Type, s.ProjectUpdate.ExtProjectID)
+	}
+	return fmt.Sprintf("%s %s", s.Type, s.ExtLineItemID)
+}
+
+// Plan lists the steps that bring a project to the state of its
+// ProjectSpec, see Client.PlanProject and Client.ApplyPlan.
+type Plan struct {
+	ExtProjectID string
+	Steps        []*PlanStep
+}
+
+// Empty reports whether the plan has no step to apply: the project matches
+// its spec, except for the line items that have finished.
+func (p *Plan) Empty() bool {
+	for _, s := range p.Steps {
+		if s.Skip == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns the error of the first step that cannot be applied, or nil.
+func (p *Plan) Err() error {
+	for _, s := range p.Steps {
+		if s.Err != nil {
+			return fmt.Errorf("%s: %w", s, s.Err)
+		}
+	}
+	return nil
+}
+
+// String returns the steps one per line, the changes of updates indented
+// below them, or "no changes". Steps are marked "+" for creations, "~" for
+// updates, ">" for state changes, "!" if they cannot be applied and "-" if
+// they are skipped.
+func (p *Plan) String() string {
+	if len(p.Steps) == 0 {
+		return "no changes"
+	}
+	lines := []string{}
+	for _, s := range p.Steps {
+		mark := "+"
+		switch s.Type {
+		case StepUpdateProject, StepUpdateLineItem:
+			mark = "~"
+		case StepLaunchLineItem, StepPauseLineItem:
+			mark = ">"
+		}
+		switch {
+		case s.Err != nil:
+			lines = append(lines, fmt.Sprintf("! %s: %v", s, s.Err))
+		case s.Skip != nil:
+			lines = append(lines, fmt.Sprintf("- %s: %v", s, s.Skip))
+		default:
+			lines = append(lines, fmt.Sprintf("%s %s", mark, s))
+		}
+		switch {
+		case s.Project != nil:
+			for _, li := range s.Project.LineItems {
+				lines = append(lines, "    + line item "+li.ExtLineItemID)
+			}
+		case s.ProjectChanges != nil:
+			for _, c := range s.ProjectChanges {
+				lines = append(lines, "    "+c.String())
+			}
+		case s.Diff != nil:
+			for _, c := range s.Diff.Changes {
+				lines = append(lines, "    "+c.String())
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// PlanProjectWithContext compares spec with the project and line items it
+// describes, and returns the steps that bring them to the spec: creating the
+// project with all its line items if it does not exist, updating the title,
+// notification emails, job number, devices and category of the project,
+// adding the missing line items, updating the fields that differ from the
+// spec, and launching or pausing the line items. Fields left out of the spec,
+// the exclusions and respondent filters of an existing project and the line
+// items missing from the spec are left unchanged. Until a line item has been
+// bought, see BuyProject, its desired state is ignored; once bought, it is
+// only launched or paused from PAUSED or LAUNCHED, and its step is skipped in
+// the other states. Line items that have finished, such as COMPLETED or
+// CLOSED ones, are not changed; their steps are skipped.
+func (c *Client) PlanProjectWithContext(ctx context.Context, spec *ProjectSpec) (*Plan, error) {
+	if err := spec.Validate(); err != nil {
+		return nil, err
+	}
+	plan := &Plan{ExtProjectID: spec.ExtProjectID}
+	project, err := c.GetProjectByWithContext(ctx, spec.ExtProjectID)
+	if errors.Is(err, ErrNotFound) {
+		plan.Steps = append(plan.Steps, &PlanStep{Type: StepCreateProject, Project: spec.Criteria()})
+		return plan, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if step := projectStep(project.Project, spec); step != nil {
+		plan.Steps = append(plan.Steps, step)
+	}
+	items, err := c.ListAllLineItemsWithContext(ctx, spec.ExtProjectID, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]bool{}
+	for _, li := range items {
+		if li != nil {
+			existing[li.ExtLineItemID] = true
+		}
+	}
+	// The line items are compared with those of the project, which are
+	// complete, unlike the listed ones.
+	current := map[string]*LineItem{}
+	if project.Project != nil {
+		for _, li := range project.Project.LineItems {
+			if li != nil {
+				current[li.ExtLineItemID] = li
+			}
+		}
+	}
+	for _, li := range nonNil(spec.LineItems) {
+		if !existing[li.ExtLineItemID] {
+			plan.Steps = append(plan.Steps, &PlanStep{
+				Type:          StepAddLineItem,
+				ExtLineItemID: li.ExtLineItemID,
+				LineItem:      &li.CreateLineItemCriteria,
+			})
+			continue
+		}
+		item, ok := current[li.ExtLineItemID]
+		if !ok {
+			res, err := c.GetLineItemByWithContext(ctx, spec.ExtProjectID, li.ExtLineItemID)
+			if err != nil {
+				return nil, err
+			}
+			if item = res.Item; item == nil {
+				item = &LineItem{}
+			}
+		}
+		plan.Steps = append(plan.Steps, lineItemSteps(item, li)...)
+	}
+	return plan, nil
+}
+
+// PlanProject ...
+func (c *Client) PlanProject(spec *ProjectSpec) (*Plan, error) {
+	return c.PlanProjectWithContext(context.Background(), spec)
+}
+
+// projectStep returns the step that updates the fields of the project current
+// that differ from spec, or nil if they do not. The job number and devices
+// are only compared if the spec sets them.
+func projectStep(current *Project, spec *ProjectSpec) *PlanStep {
+	if current == nil {
+		current = &Project{}
+	}
+	u := &UpdateProjectCriteria{ExtProjectID: spec.ExtProjectID}
+	var changes Changes
+	if current.Title != spec.Title {
+		changes.add(ChangeModified, "title", current.Title, spec.Title)
+		u.Title = &spec.Title
+	}
+	if !sameOptions(current.NotificationEmails, spec.NotificationEmails) {
+		changes.add(ChangeModified, "notificationEmails", current.NotificationEmails, spec.NotificationEmails)
+		u.NotificationEmails = &spec.NotificationEmails
+	}
+	if spec.JobNumber != "" && current.JobNumber != spec.JobNumber {
+		changes.add(ChangeModified, "jobNumber", current.JobNumber, spec.JobNumber)
+		u.JobNumber = &spec.JobNumber
+	}
+	if spec.Devices != nil && !reflect.DeepEqual(current.Devices, spec.Devices) {
+		changes.add(ChangeModified, "devices", current.Devices, spec.Devices)
+		u.Devices = &spec.Devices
+	}
+	if !reflect.DeepEqual(current.Category, spec.Category) {
+		changes.add(ChangeModified, "category", current.Category, spec.Category)
+		u.Category = spec.Category
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return &PlanStep{Type: StepUpdateProject, ProjectUpdate: u, ProjectChanges: changes}
+}
+
+// lineItemSteps returns the steps that bring the line item current to spec.
+func lineItemSteps(current *LineItem, spec *LineItemSpec) []*PlanStep {
+	var finished error
+	if finalStates[current.State] {
+		finished = fmt.Errorf("%w: %s is %s", ErrLineItemFinished, spec.ExtLineItemID, current.State)
+	}
+	steps := []*PlanStep{}
+	diff := DiffLineItem(current, spec.updateCriteria())
+	if !diff.Empty() {
+		step := &PlanStep{Type: StepUpdateLineItem, ExtLineItemID: spec.ExtLineItemID, Diff: diff, Skip: finished}
+		if finished == nil && !current.IsUpdateable() {
+			step.Err = fmt.Errorf("%w: %s is %s", ErrLineItemNotUpdateable, spec.ExtLineItemID, current.State)
+		}
+		steps = append(steps, step)
+	}
+
+	var action Action
+	step := &PlanStep{ExtLineItemID: spec.ExtLineItemID, Skip: finished}
+	switch spec.State {
+	case StateLaunched:
+		action, step.Type = ActionLaunched, StepLaunchLineItem
+	case StatePaused:
+		action, step.Type = ActionPaused, StepPauseLineItem
+	default:
+		return steps
+	}
+	switch state := current.State; {
+	case state == spec.State, current.IsBuyable():
+		// Already in the desired state, or not bought yet.
+		return steps
+	case finished != nil:
+	case !state.leadsTo(action, spec.State):
+		step.Skip = fmt.Errorf("%w: %s is %s", ErrLineItemNotInField, spec.ExtLineItemID, state)
+	}
+	return append(steps, step)
+}
+
+// ApplyPlanWithContext applies the steps of plan in order, through
+// CreateProject, UpdateProject, AddLineItem, UpdateLineItem and UpdateLineItemState, leaving
+// out the skipped ones. Nothing is applied if a step cannot be, see Plan.Err.
+// It stops at the first error, returned along with the failed step; planning
+// again shows the steps that remain.
+func (c *Client) ApplyPlanWithContext(ctx context.Context, plan *Plan) error {
+	if err := plan.Err(); err != nil {
+		return err
+	}
+	for _, s := range plan.Steps {
+		if s.Skip != nil {
+			continue
+		}
+		var err error
+		switch s.Type {
+		case StepCreateProject:
+			_, err = c.CreateProjectWithContext(ctx, s.Project)
+		case StepUpdateProject:
+			_, err = c.UpdateProjectWithContext(ctx, s.ProjectUpdate)
+		case StepAddLineItem:
+			_, err = c.AddLineItemWithContext(ctx, plan.ExtProjectID, s.LineItem)
+		case StepUpdateLineItem:
+			_, err = c.UpdateLineItemWithContext(ctx, plan.ExtProjectID, s.ExtLineItemID, s.Diff.Criteria())
+		case StepLaunchLineItem:
+			_, err = c.UpdateLineItemStateWithContext(ctx, plan.ExtProjectID, s.ExtLineItemID, ActionLaunched)
+		case StepPauseLineItem:
+			_, err = c.UpdateLineItemStateWithContext(ctx, plan.ExtProjectID, s.ExtLineItemID, ActionPaused)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", s, err)
+		}
+	}
+	return nil
+}
+
+// ApplyPlan ...
+func (c *Client) ApplyPlan(plan *Plan) error {
+	return c.ApplyPlanWithContext(context.Background(), plan)
+}
@@ -0,0 +1,204 @@
+package samplify_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+	"github.com/researchnow/go-samplifyapi-client/lib/samplifytest"
+)
+
+const testSpec = `
+extProjectId: p1
+title: Test Survey
+notificationEmails: [api-test@researchnow.com]
+category:
+  surveyTopic: [AUTOMOTIVE]
+lineItems:
+  - extLineItemId: l1
+    title: US College
+    countryISOCode: US
+    languageISOCode: en
+    indicativeIncidence: 20
+    daysInField: 10
+    lengthOfInterview: 10
+    requiredCompletes: 200
+    state: LAUNCHED
+    quotaPlan:
+      filters:
+        - attributeId: "11"
+          options: ["1", "2"]
+          operator: include
+`
+
+func writeSpec(t *testing.T, name, spec string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(spec), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadSpec(t *testing.T) {
+	spec, err := samplify.LoadSpec(writeSpec(t, "p1.yaml", testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.ExtProjectID != "p1" || len(spec.LineItems) != 1 {
+		t.Fatalf("unexpected spec %+v", spec)
+	}
+	li := spec.LineItems[0]
+	if li.ExtLineItemID != "l1" || li.State != samplify.StateLaunched || li.IndicativeIncidence != 20 ||
+		li.QuotaPlan == nil || li.QuotaPlan.Filters[0].Options[1] != "2" {
+		t.Errorf("unexpected line item %+v", li)
+	}
+	if c := spec.Criteria(); len(c.LineItems) != 1 || c.LineItems[0] != &li.CreateLineItemCriteria {
+		t.Errorf("unexpected criteria %+v", c)
+	}
+
+	json := `{"extProjectId": "p1", "lineItems": [{"extLineItemId": "l1", "state": "PAUSED"}]}`
+	spec, err = samplify.LoadSpec(writeSpec(t, "p1.json", json))
+	if err != nil || spec.LineItems[0].State != samplify.StatePaused {
+		t.Errorf("unexpected spec %+v, %v", spec, err)
+	}
+
+	for name, s := range map[string]string{"typo.yaml": "extProjectId: p1\ntitel: x\n", "typo.json": `{"titel": "x"}`} {
+		if _, err := samplify.LoadSpec(writeSpec(t, name, s)); err == nil || !strings.Contains(err.Error(), "titel") {
+			t.Errorf("%s: expected an unknown field error, got %v", name, err)
+		}
+	}
+}
+
+func TestPlanProject(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	client := s.NewClient()
+	spec, err := samplify.LoadSpec(writeSpec(t, "p1.yaml", testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := client.PlanProject(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "+ create project p1\n    + line item l1"; plan.String() != want {
+		t.Errorf("expected plan\n%s\ngot\n%s", want, plan)
+	}
+	if err := client.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	// The line item is not launched before it is bought.
+	if plan, err = client.PlanProject(spec); err != nil || !plan.Empty() {
+		t.Errorf("expected no changes, got %v, %v", plan, err)
+	}
+
+	l2 := *spec.LineItems[0]
+	l2.ExtLineItemID, l2.State = "l2", ""
+	spec.LineItems = append(spec.LineItems, &l2)
+	spec.LineItems[0].Title = "US Graduates"
+	spec.Title = "Graduate Survey"
+	plan, err = client.PlanProject(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `~ update project p1
+    ~ title: "Test Survey" -> "Graduate Survey"
+~ update line item l1
+    ~ title: "US College" -> "US Graduates"
++ add line item l2`
+	if plan.String() != want {
+		t.Errorf("expected plan\n%s\ngot\n%s", want, plan)
+	}
+	if err := client.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.Project("p1"); p.Title != "Graduate Survey" {
+		t.Errorf("expected the project to be updated, got %q", p.Title)
+	}
+
+	// A paused line item is launched, but cannot be updated.
+	if err := s.SetLineItemState("p1", "l1", samplify.StatePaused); err != nil {
+		t.Fatal(err)
+	}
+	spec.LineItems[0].Title = "US Students"
+	if plan, err = client.PlanProject(spec); err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Steps) != 2 || plan.Steps[1].Type != samplify.StepLaunchLineItem ||
+		!strings.HasPrefix(plan.String(), "! update line item l1: line item cannot be updated") {
+		t.Errorf("unexpected plan\n%s", plan)
+	}
+	if err := client.ApplyPlan(plan); !errors.Is(err, samplify.ErrLineItemNotUpdateable) {
+		t.Errorf("expected ErrLineItemNotUpdateable, got %v", err)
+	}
+
+	spec.LineItems[0].Title = "US Graduates"
+	if plan, err = client.PlanProject(spec); err != nil || plan.String() != "> launch line item l1" {
+		t.Fatalf("unexpected plan %v, %v", plan, err)
+	}
+	if err := client.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	if p, _ := s.Project("p1"); p.LineItems[0].State != samplify.StateLaunched || p.LineItems[0].Title != "US Graduates" {
+		t.Errorf("unexpected line item %+v", p.LineItems[0])
+	}
+
+	// A bought line item that is not in the field yet is not launched.
+	for _, state := range []samplify.State{samplify.StateAwaitingApproval, samplify.StateQAApproved, samplify.StateAwaitingClientApproval} {
+		if err := s.SetLineItemState("p1", "l1", state); err != nil {
+			t.Fatal(err)
+		}
+		if plan, err = client.PlanProject(spec); err != nil || plan.Err() != nil || !plan.Empty() ||
+			plan.String() != "- launch line item l1: line item is neither launched nor paused: l1 is "+string(state) {
+			t.Errorf("unexpected plan %v, %v", plan, err)
+		}
+	}
+
+	// A finished line item is left alone without blocking the other steps,
+	// and the line items are not fetched one by one.
+	if err := s.SetLineItemState("p1", "l1", samplify.StateCompleted); err != nil {
+		t.Fatal(err)
+	}
+	var fetched int32
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/lineItems/") {
+			atomic.AddInt32(&fetched, 1)
+		}
+		return false
+	})
+	l3 := l2
+	l3.ExtLineItemID = "l3"
+	spec.LineItems = append(spec.LineItems, &l3)
+	spec.LineItems[0].Title = "US Alumni"
+	if plan, err = client.PlanProject(spec); err != nil {
+		t.Fatal(err)
+	}
+	want = `- update line item l1: line item has finished and is not changed anymore: l1 is COMPLETED
+    ~ title: "US Graduates" -> "US Alumni"
+- launch line item l1: line item has finished and is not changed anymore: l1 is COMPLETED
++ add line item l3`
+	if plan.String() != want || plan.Err() != nil || plan.Empty() {
+		t.Errorf("expected plan\n%s\ngot\n%s", want, plan)
+	}
+	if n := atomic.LoadInt32(&fetched); n != 0 {
+		t.Errorf("expected no line item to be fetched, got %d", n)
+	}
+	if err := client.ApplyPlan(plan); err != nil {
+		t.Fatal(err)
+	}
+	if plan, err = client.PlanProject(spec); err != nil || !plan.Empty() || plan.Steps[0].Skip == nil {
+		t.Errorf("expected only skipped steps, got %v, %v", plan, err)
+	}
+
+	spec.LineItems[0].State = "OPEN"
+	if _, err := client.PlanProject(spec); !errors.Is(err, samplify.ErrInvalidSpecState) {
+		t.Errorf("expected ErrInvalidSpecState, got %v", err)
+	}
+}
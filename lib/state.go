@@ -0,0 +1,161 @@
+package samplify
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTransition ... Matched by a *TransitionError with errors.Is
+var ErrInvalidTransition = errors.New("action not allowed in the current state")
+
+// actions lists the Action values in the order they are reported.
+var actions = []Action{ActionLaunched, ActionPaused, ActionClosed}
+
+// stateRules describes what can be done with a line item in a state.
+type stateRules struct {
+	// next maps the actions allowed in the state to the states they lead to.
+	next map[Action]State
+	// updateable, buyable and rebalanceable back IsUpdateable, IsBuyable and
+	// IsRebalanceable.
+	updateable, buyable, rebalanceable bool
+}
+
+// lineItemStates lists the rules for every State. Closing is allowed in every
+// state but CLOSED, CANCELLED and INVOICED, and closed, cancelled and
+// invoiced line items cannot change any more. States missing from the map,
+// such as ones added to the API later, allow every action and leave the
+// decision to the API.
+var lineItemStates = map[State]stateRules{
+	// A line item is drafted until it is bought. It cannot be launched or
+	// paused before; buying it sends it for approval.
+	StateProvisioned: {
+		next:       map[Action]State{ActionClosed: StateClosed},
+		updateable: true, buyable: true, rebalanceable: true,
+	},
+	// A bought line item awaits approval, and can be paused and resumed
+	// while it does. It is launched once approved, not by the client.
+	StateAwaitingApproval: {
+		next:          map[Action]State{ActionPaused: StateAwaitingApprovalPaused, ActionClosed: StateClosed},
+		rebalanceable: true,
+	},
+	StateAwaitingApprovalPaused: {
+		next:          map[Action]State{ActionLaunched: StateAwaitingApproval, ActionClosed: StateClosed},
+		rebalanceable: true,
+	},
+	StateQAApproved: {
+		next:          map[Action]State{ActionClosed: StateClosed},
+		rebalanceable: true,
+	},
+	// A line item awaiting the client's approval is accepted or rejected
+	// through its event, see AcceptEvent and RejectEvent.
+	StateAwaitingClientApproval: {
+		next: map[Action]State{ActionClosed: StateClosed},
+	},
+	// A rejected line item can be updated and bought again, and paused and
+	// resumed in the meantime.
+	StateRejected: {
+		next:       map[Action]State{ActionPaused: StateRejectedPaused, ActionClosed: StateClosed},
+		updateable: true, buyable: true, rebalanceable: true,
+	},
+	StateRejectedPaused: {
+		next:    map[Action]State{ActionLaunched: StateRejected, ActionClosed: StateClosed},
+		buyable: true, rebalanceable: true,
+	},
+	// The pause and launch endpoints move a line item in the field between
+	// LAUNCHED and PAUSED.
+	StateLaunched: {
+		next:          map[Action]State{ActionPaused: StatePaused, ActionClosed: StateClosed},
+		rebalanceable: true,
+	},
+	StatePaused: {
+		next:          map[Action]State{ActionLaunched: StateLaunched, ActionClosed: StateClosed},
+		rebalanceable: true,
+	},
+	StateCompleted: {
+		next:          map[Action]State{ActionClosed: StateClosed},
+		rebalanceable: true,
+	},
+	StateClosed:    {},
+	StateCancelled: {},
+	StateInvoiced:  {},
+}
+
+// AllowedActions returns the actions that can be applied to a line item in
+// state s. States unknown to the client allow every action, leaving the
+// decision to the API.
+func (s State) AllowedActions() []Action {
+	allowed := []Action{}
+	for _, a := range actions {
+		if s.Allows(a) {
+			allowed = append(allowed, a)
+		}
+	}
+	return allowed
+}
+
+// Allows reports whether action can be applied to a line item in state s.
+func (s State) Allows(action Action) bool {
+	r, ok := lineItemStates[s]
+	if !ok {
+		return true
+	}
+	_, ok = r.next[action]
+	return ok
+}
+
+// Next returns the state that a line item in state s moves to when action is
+// applied, or a *TransitionError if action is not allowed in s. For states
+// unknown to the client, s is returned.
+func (s State) Next(action Action) (State, error) {
+	r, ok := lineItemStates[s]
+	if !ok {
+		return s, nil
+	}
+	next, ok := r.next[action]
+	if !ok {
+		return s, &TransitionError{State: s, Action: action, Allowed: s.AllowedActions()}
+	}
+	return next, nil
+}
+
+// leadsTo reports whether the transitions of the client take a line item in
+// state s to state to when action is applied. It is false for states unknown
+// to the client.
+func (s State) leadsTo(action Action, to State) bool {
+	next, ok := lineItemStates[s].next[action]
+	return ok && next == to
+}
+
+// TransitionError is returned when an action is not allowed in the current
+// state of a line item.
+type TransitionError struct {
+	ExtProjectID  string
+	ExtLineItemID string
+	State         State
+	Action        Action
+	// Allowed are the actions allowed in State.
+	Allowed []Action
+}
+
+// Error ...
+func (e *TransitionError) Error() string {
+	allowed := make([]string, len(e.Allowed))
+	for i, a := range e.Allowed {
+		allowed[i] = string(a)
+	}
+	if len(allowed) == 0 {
+		allowed = append(allowed, "none")
+	}
+	item := "line item"
+	if len(e.ExtLineItemID) > 0 {
+		item = fmt.Sprintf("line item %s", e.ExtLineItemID)
+	}
+	return fmt.Sprintf("cannot %s %s in state %s, allowed actions: %s",
+		e.Action, item, e.State, strings.Join(allowed, ", "))
+}
+
+// Is reports whether target is ErrInvalidTransition.
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
@@ -0,0 +1,93 @@
+package samplify_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+func TestLineItemTransitions(t *testing.T) {
+	tests := []struct {
+		state   samplify.State
+		allowed []samplify.Action
+	}{
+		{samplify.StateCancelled, []samplify.Action{}},
+		{samplify.StateLaunched, []samplify.Action{samplify.ActionPaused, samplify.ActionClosed}},
+		{samplify.StatePaused, []samplify.Action{samplify.ActionLaunched, samplify.ActionClosed}},
+		{samplify.StateClosed, []samplify.Action{}},
+		{samplify.StateProvisioned, []samplify.Action{samplify.ActionClosed}},
+		{samplify.StateAwaitingApproval, []samplify.Action{samplify.ActionPaused, samplify.ActionClosed}},
+		{samplify.StateQAApproved, []samplify.Action{samplify.ActionClosed}},
+		{samplify.StateCompleted, []samplify.Action{samplify.ActionClosed}},
+		{samplify.State("SOMETHING_NEW"), []samplify.Action{samplify.ActionLaunched, samplify.ActionPaused, samplify.ActionClosed}},
+	}
+	for _, tt := range tests {
+		if allowed := tt.state.AllowedActions(); !reflect.DeepEqual(allowed, tt.allowed) {
+			t.Errorf("%s: expected %v, got %v", tt.state, tt.allowed, allowed)
+		}
+	}
+
+	if next, err := samplify.StatePaused.Next(samplify.ActionLaunched); err != nil || next != samplify.StateLaunched {
+		t.Errorf("expected PAUSED to launch into LAUNCHED, got %s, %v", next, err)
+	}
+	if _, err := samplify.StateClosed.Next(samplify.ActionLaunched); !errors.Is(err, samplify.ErrInvalidTransition) {
+		t.Errorf("expected ErrInvalidTransition, got %v", err)
+	}
+
+	items := []struct {
+		state                                  samplify.State
+		updateable, buyable, rebalanceable, ok bool
+	}{
+		{samplify.StateProvisioned, true, true, true, true},
+		{samplify.StateRejectedPaused, false, true, true, true},
+		{samplify.StateLaunched, false, false, true, true},
+		{samplify.StateAwaitingClientApproval, false, false, false, true},
+		{samplify.StateInvoiced, false, false, false, false},
+		{samplify.State("SOMETHING_NEW"), false, false, true, true},
+	}
+	for _, tt := range items {
+		l := &samplify.LineItem{}
+		l.State = tt.state
+		if l.IsUpdateable() != tt.updateable || l.IsBuyable() != tt.buyable ||
+			l.IsRebalanceable() != tt.rebalanceable || l.IsCloseable() != tt.ok {
+			t.Errorf("%s: unexpected rules %v %v %v %v", tt.state,
+				l.IsUpdateable(), l.IsBuyable(), l.IsRebalanceable(), l.IsCloseable())
+		}
+	}
+}
+
+func TestUpdateLineItemStateCheck(t *testing.T) {
+	var urls []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		urls = append(urls, r.Method+" "+r.URL.Path)
+		w.Write([]byte(`{"data":{"extLineItemId":"l1","state":"CLOSED"}}`))
+	}))
+	defer ts.Close()
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{APIBaseURL: ts.URL})
+	client.Auth = getAuth()
+
+	_, err := client.LaunchLineItem("p1", "l1")
+	var terr *samplify.TransitionError
+	if !errors.As(err, &terr) || terr.State != samplify.StateClosed || len(terr.Allowed) != 0 {
+		t.Fatalf("expected a TransitionError, got %v", err)
+	}
+	if err.Error() != "cannot launch line item l1 in state CLOSED, allowed actions: none" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+	if len(urls) != 1 || urls[0] != "GET /projects/p1/lineItems/l1" {
+		t.Errorf("expected only the line item to be fetched, got %v", urls)
+	}
+
+	urls = nil
+	client.Options.SkipStateCheck = true
+	if _, err := client.LaunchLineItem("p1", "l1"); err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 || urls[0] != "POST /projects/p1/lineItems/l1/launch" {
+		t.Errorf("expected the state change to be sent without a check, got %v", urls)
+	}
+}
@@ -7,6 +7,15 @@ type TeamsResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *TeamsResponse) Envelope() *ListEnvelope[*CompanyTeam] {
+	return &ListEnvelope[*CompanyTeam]{Data: r.List, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *TeamsResponse) setEnvelope(e *ListEnvelope[*CompanyTeam]) {
+	r.List, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // CompanyTeam holds info about a company team
 type CompanyTeam struct {
 	ID          int32       `json:"id"`
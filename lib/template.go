@@ -7,6 +7,15 @@ type AppError struct {
 	Status *Status     `json:"status"`
 }
 
+// Envelope returns e as an Envelope.
+func (e *AppError) Envelope() *Envelope[interface{}] {
+	return &Envelope[interface{}]{Data: e.Data, ResponseStatus: derefStatus(e.Status), Meta: derefMeta(e.Meta)}
+}
+
+func (e *AppError) setEnvelope(env *Envelope[interface{}]) {
+	e.Data, e.Meta, e.Status = env.Data, &env.Meta, &env.ResponseStatus
+}
+
 // TemplateCriteria ...
 type TemplateCriteria struct {
 	CountryISOCode  string     `json:"countryISOCode"`
@@ -17,18 +26,11 @@ type TemplateCriteria struct {
 	Tags            []string   `json:"tags"`
 }
 
-// ErrorType user type.
-type ErrorType struct {
-	Code     string    `json:"code"`
-	Message  string    `json:"message"`
-	Resource *Resource `json:"resource"`
-}
+// ErrorType is the ErrorInfo of the template requests.
+type ErrorType = ErrorInfo
 
-// Status user type.
-type Status struct {
-	Errors  []*ErrorType `json:"errors"`
-	Message string       `json:"message"`
-}
+// Status is the ResponseStatus of the template requests.
+type Status = ResponseStatus
 
 // TemplateResponse response
 type TemplateResponse struct {
@@ -37,6 +39,15 @@ type TemplateResponse struct {
 	Status *Status       `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *TemplateResponse) Envelope() *Envelope[*TemplateData] {
+	return &Envelope[*TemplateData]{Data: r.Data, ResponseStatus: derefStatus(r.Status), Meta: derefMeta(r.Meta)}
+}
+
+func (r *TemplateResponse) setEnvelope(e *Envelope[*TemplateData]) {
+	r.Data, r.Meta, r.Status = e.Data, &e.Meta, &e.ResponseStatus
+}
+
 // TemplatesResponse response
 type TemplatesResponse struct {
 	Data   []*TemplateData `json:"data"`
@@ -44,6 +55,29 @@ type TemplatesResponse struct {
 	Status *Status         `json:"status"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *TemplatesResponse) Envelope() *ListEnvelope[*TemplateData] {
+	return &ListEnvelope[*TemplateData]{Data: r.Data, ResponseStatus: derefStatus(r.Status), Meta: derefMeta(r.Meta)}
+}
+
+func (r *TemplatesResponse) setEnvelope(e *ListEnvelope[*TemplateData]) {
+	r.Data, r.Meta, r.Status = e.Data, &e.Meta, &e.ResponseStatus
+}
+
+func derefStatus(s *Status) ResponseStatus {
+	if s == nil {
+		return ResponseStatus{}
+	}
+	return *s
+}
+
+func derefMeta(m *Meta) Meta {
+	if m == nil {
+		return Meta{}
+	}
+	return *m
+}
+
 // TemplateData ...
 type TemplateData struct {
 	CountryISOCode  *string    `json:"countryISOCode,omitempty"`
@@ -18,20 +18,33 @@ type TokenResponse struct {
 	Acquired         *time.Time
 }
 
-// AccessTokenExpired ...
+// tokenExpiryMargin is how long before their actual expiry tokens are treated
+// as expired, so that they do not expire while a request is in flight. Tokens
+// with a short lifetime use a tenth of it instead.
+const tokenExpiryMargin = 30 * time.Second
+
+// AccessTokenExpired reports whether the access token is missing or about to
+// expire.
 func (t *TokenResponse) AccessTokenExpired() bool {
-	if len(t.AccessToken) == 0 || t.Acquired == nil ||
-		time.Since(*t.Acquired).Seconds() > float64(t.ExpiresIn) {
-		return true
-	}
-	return false
+	return len(t.AccessToken) == 0 || expired(t.Acquired, t.ExpiresIn)
 }
 
-// RefreshTokenExpired ...
+// RefreshTokenExpired reports whether the refresh token is missing or about to
+// expire.
 func (t *TokenResponse) RefreshTokenExpired() bool {
-	if len(t.RefreshToken) == 0 || t.Acquired == nil ||
-		time.Since(*t.Acquired).Seconds() > float64(t.RefreshExpiresIn) {
+	return len(t.RefreshToken) == 0 || expired(t.Acquired, t.RefreshExpiresIn)
+}
+
+// expired reports whether a token acquired at the given time and valid for
+// expiresIn seconds is within tokenExpiryMargin of its expiry.
+func expired(acquired *time.Time, expiresIn uint) bool {
+	if acquired == nil {
 		return true
 	}
-	return false
+	lifetime := time.Duration(expiresIn) * time.Second
+	margin := tokenExpiryMargin
+	if lifetime/10 < margin {
+		margin = lifetime / 10
+	}
+	return time.Since(*acquired) > lifetime-margin
 }
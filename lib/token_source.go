@@ -0,0 +1,170 @@
+package samplify
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrTokenFileCorrupt ... Returns if a token file cannot be decrypted with the given key
+var ErrTokenFileCorrupt = errors.New("token file is corrupt or was encrypted with a different key")
+
+// TokenSource stores the tokens a Client authenticates with. The client reads
+// the tokens before its first request and whenever they need to be renewed, and
+// stores the tokens it acquires. Implementations must be safe for concurrent use.
+type TokenSource interface {
+	// Token returns the stored tokens, or nil if there are none.
+	Token() (*TokenResponse, error)
+	// SetToken stores newly acquired tokens.
+	SetToken(t *TokenResponse) error
+}
+
+// memoryTokenSource keeps tokens in memory only.
+type memoryTokenSource struct {
+	mu    sync.Mutex
+	token *TokenResponse
+}
+
+// NewMemoryTokenSource returns a TokenSource that keeps tokens in memory,
+// starting with t which may be nil. This is the default for a Client.
+func NewMemoryTokenSource(t *TokenResponse) TokenSource {
+	s := &memoryTokenSource{}
+	if t != nil {
+		s.SetToken(t)
+	}
+	return s
+}
+
+// Token ...
+func (s *memoryTokenSource) Token() (*TokenResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == nil {
+		return nil, nil
+	}
+	t := *s.token
+	return &t, nil
+}
+
+// SetToken ...
+func (s *memoryTokenSource) SetToken(t *TokenResponse) error {
+	c := *t
+	s.mu.Lock()
+	s.token = &c
+	s.mu.Unlock()
+	return nil
+}
+
+// staticTokenSource always returns the same tokens.
+type staticTokenSource struct {
+	token TokenResponse
+}
+
+// StaticTokenSource returns a TokenSource that always returns t and discards
+// the tokens the client acquires. Use it to authenticate with tokens obtained
+// elsewhere.
+func StaticTokenSource(t TokenResponse) TokenSource {
+	return &staticTokenSource{token: t}
+}
+
+// Token ...
+func (s *staticTokenSource) Token() (*TokenResponse, error) {
+	t := s.token
+	return &t, nil
+}
+
+// SetToken ...
+func (s *staticTokenSource) SetToken(t *TokenResponse) error {
+	return nil
+}
+
+// FileTokenSource stores tokens in a file, encrypted with AES-GCM, so that they
+// can be reused across process restarts.
+type FileTokenSource struct {
+	path string
+	aead cipher.AEAD
+	mu   sync.Mutex
+}
+
+// NewFileTokenSource returns a TokenSource that stores tokens in the file at
+// path, encrypted with key. The key must be 16, 24 or 32 bytes long to select
+// AES-128, AES-192 or AES-256.
+func NewFileTokenSource(path string, key []byte) (*FileTokenSource, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &FileTokenSource{path: path, aead: aead}, nil
+}
+
+// Token returns the tokens stored in the file, or nil if the file does not
+// exist.
+func (s *FileTokenSource) Token() (*TokenResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	n := s.aead.NonceSize()
+	if len(data) < n {
+		return nil, ErrTokenFileCorrupt
+	}
+	plain, err := s.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, ErrTokenFileCorrupt
+	}
+	t := &TokenResponse{}
+	err = json.Unmarshal(plain, t)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// SetToken encrypts t and replaces the contents of the file with it. The file
+// is only readable by the current user.
+func (s *FileTokenSource) SetToken(t *TokenResponse) error {
+	plain, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return err
+	}
+	data := s.aead.Seal(nonce, nonce, plain, nil)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Write to a temporary file first so that a concurrent reader never sees
+	// a partially written file.
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
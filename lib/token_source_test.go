@@ -0,0 +1,147 @@
+package samplify_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+var testTokenKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestFileTokenSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "samplify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens")
+
+	src, err := samplify.NewFileTokenSource(path, testTokenKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := src.Token()
+	if err != nil || tok != nil {
+		t.Fatalf("expected no tokens before the first write, got %v, %v", tok, err)
+	}
+
+	want := getAuth()
+	want.RefreshToken = "secret-refresh-token"
+	if err := src.SetToken(&want); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(want.RefreshToken)) {
+		t.Error("token file is not encrypted")
+	}
+
+	tok, err = src.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.AccessToken != want.AccessToken || tok.RefreshToken != want.RefreshToken ||
+		!tok.Acquired.Equal(*want.Acquired) {
+		t.Errorf("expected %+v, got %+v", want, tok)
+	}
+
+	other, err := samplify.NewFileTokenSource(path, []byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Token(); err != samplify.ErrTokenFileCorrupt {
+		t.Errorf("expected ErrTokenFileCorrupt with the wrong key, got %v", err)
+	}
+}
+
+func TestFileTokenSourceReusedAcrossClients(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	dir, err := ioutil.TempDir("", "samplify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens")
+
+	for i := 0; i < 3; i++ {
+		src, err := samplify.NewFileTokenSource(path, testTokenKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client := s.client(samplify.TokenResponse{})
+		client.TokenSource = src
+		if _, err := client.GetAllProjects(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := atomic.LoadInt32(&s.logins); n != 1 {
+		t.Errorf("expected 1 login, got %d", n)
+	}
+}
+
+func TestStaticTokenSource(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	tok, err := s.client(samplify.TokenResponse{}).GetAuth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := s.client(samplify.TokenResponse{})
+	client.TokenSource = samplify.StaticTokenSource(tok)
+	if _, err := client.GetAllProjects(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&s.logins); n != 1 {
+		t.Errorf("expected the static tokens to be used, got %d logins", n)
+	}
+}
+
+// failingTokenSource cannot store tokens.
+type failingTokenSource struct{}
+
+var errReadOnly = errors.New("read-only token source")
+
+func (failingTokenSource) Token() (*samplify.TokenResponse, error) { return nil, nil }
+
+func (failingTokenSource) SetToken(*samplify.TokenResponse) error { return errReadOnly }
+
+func TestTokenSourceStoreError(t *testing.T) {
+	s := newAuthServer()
+	defer s.Close()
+	client := s.client(samplify.TokenResponse{})
+	client.TokenSource = failingTokenSource{}
+	errs := make(chan error, 1)
+	client.Options.BackgroundRefresh = &samplify.BackgroundRefreshOptions{
+		OnError: func(err error) { errs <- err },
+	}
+	defer client.Close()
+
+	// The tokens are still used from memory.
+	if _, err := client.GetAllProjects(nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetAllProjects(nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&s.logins); n != 1 {
+		t.Errorf("expected 1 login, got %d", n)
+	}
+	select {
+	case err := <-errs:
+		if !errors.Is(err, errReadOnly) {
+			t.Errorf("expected the storage error, got %v", err)
+		}
+	default:
+		t.Error("expected OnError to be called")
+	}
+}
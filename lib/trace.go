@@ -0,0 +1,171 @@
+package samplify
+
+import (
+	"context"
+	"net/http"
+)
+
+// Tracer starts the spans of a Client. It is modelled on OpenTelemetry so
+// that an adapter around a trace.Tracer and a propagator takes a few lines,
+// without making this package depend on OpenTelemetry.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, if any, and returns
+	// a context that carries it.
+	Start(ctx context.Context, name string) (context.Context, Span)
+	// Inject adds the trace context of ctx to the headers of an outgoing
+	// request, e.g. the W3C traceparent header.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is an operation traced by a Tracer.
+type Span interface {
+	SetAttribute(key string, value interface{})
+	// RecordError records err and marks the span as failed.
+	RecordError(err error)
+	End()
+}
+
+// Span attributes set by a Client
+const (
+	AttrExtProjectID  = "samplify.extProjectId"
+	AttrExtLineItemID = "samplify.extLineItemId"
+	AttrRequestID     = "samplify.requestId"
+	AttrAttempt       = "samplify.attempt"
+	AttrRetryDelay    = "samplify.retryDelay" // in seconds
+	AttrMethod        = "http.request.method"
+	AttrPath          = "url.path"
+	AttrStatusCode    = "http.response.status_code"
+)
+
+// UnknownOperation names the requests that are not sent by a Client method.
+const UnknownOperation = "unknown"
+
+// operation is the Client method, or the token request, that requests are
+// sent for. It names the requests in metrics and, if tracing is enabled,
+// holds the span of the method.
+type operation struct {
+	name string
+	span Span
+}
+
+type operationKey struct{}
+
+// startOperation returns a context for the requests of the named operation
+// and starts its span if the client has a Tracer. attrs are alternating keys
+// and values set on the span. The operation must be ended.
+func (c *Client) startOperation(ctx context.Context, name string, attrs ...interface{}) (context.Context, *operation) {
+	op := &operation{name: name}
+	if t := c.Options.tracer(); t != nil {
+		ctx, op.span = t.Start(ctx, name)
+		setAttributes(op.span, attrs)
+	}
+	return context.WithValue(ctx, operationKey{}, op), op
+}
+
+// operationFrom returns the operation of ctx, or nil.
+func operationFrom(ctx context.Context) *operation {
+	op, _ := ctx.Value(operationKey{}).(*operation)
+	return op
+}
+
+// operationOf returns the name of the operation of ctx, or UnknownOperation.
+func operationOf(ctx context.Context) string {
+	if op := operationFrom(ctx); op != nil {
+		return op.name
+	}
+	return UnknownOperation
+}
+
+// setAttribute sets an attribute on the span of o, if any.
+func (o *operation) setAttribute(key string, value interface{}) {
+	if o != nil && o.span != nil {
+		o.span.SetAttribute(key, value)
+	}
+}
+
+// fail records err on the span of o, if any, and returns err.
+func (o *operation) fail(err error) error {
+	if o != nil && o.span != nil && err != nil {
+		o.span.RecordError(err)
+	}
+	return err
+}
+
+// end ends the span of o, if any.
+func (o *operation) end() {
+	if o != nil && o.span != nil {
+		o.span.End()
+	}
+}
+
+func setAttributes(span Span, attrs []interface{}) {
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if key, ok := attrs[i].(string); ok {
+			span.SetAttribute(key, attrs[i+1])
+		}
+	}
+}
+
+// tracer returns the tracer of o, or nil if tracing is disabled.
+func (o *ClientOptions) tracer() Tracer {
+	if o == nil {
+		return nil
+	}
+	return o.Tracer
+}
+
+// traceTransport starts a span for every request sent with next and
+// propagates its trace context in the request headers.
+type traceTransport struct {
+	next   http.RoundTripper
+	tracer Tracer
+}
+
+// newTraceTransport wraps next with the tracer of o, or returns next if
+// tracing is disabled.
+func (o *ClientOptions) newTraceTransport(next http.RoundTripper) http.RoundTripper {
+	if o.tracer() == nil {
+		return next
+	}
+	return &traceTransport{next: next, tracer: o.Tracer}
+}
+
+// RoundTrip sends req within a span named after its method, e.g. "HTTP GET".
+// Its status and request ID are also set on the span of the operation.
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := t.tracer.Start(req.Context(), "HTTP "+req.Method)
+	defer span.End()
+	span.SetAttribute(AttrMethod, req.Method)
+	span.SetAttribute(AttrPath, req.URL.Path)
+	span.SetAttribute(AttrAttempt, attemptOf(ctx))
+
+	// A RoundTripper must not modify the request it is given.
+	req = req.Clone(ctx)
+	t.tracer.Inject(ctx, req.Header)
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		return resp, err
+	}
+	op := operationFrom(ctx)
+	span.SetAttribute(AttrStatusCode, resp.StatusCode)
+	op.setAttribute(AttrStatusCode, resp.StatusCode)
+	if id := resp.Header.Get("x-request-id"); len(id) > 0 {
+		span.SetAttribute(AttrRequestID, id)
+		op.setAttribute(AttrRequestID, id)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.RecordError(&httpStatusError{status: resp.Status})
+	}
+	return resp, nil
+}
+
+// httpStatusError is recorded on the span of a request that failed with an
+// HTTP error status.
+type httpStatusError struct {
+	status string
+}
+
+func (e *httpStatusError) Error() string {
+	return "HTTP " + e.status
+}
@@ -0,0 +1,185 @@
+package samplify_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+	"github.com/researchnow/go-samplifyapi-client/lib/samplifytest"
+)
+
+// testTracer records spans and propagates their names in a header.
+type testTracer struct {
+	mu    sync.Mutex
+	spans []*testSpan
+}
+
+type testSpan struct {
+	tracer *testTracer
+	name   string
+	parent *testSpan
+	attrs  map[string]interface{}
+	errs   []error
+	ended  bool
+}
+
+type spanKey struct{}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, samplify.Span) {
+	parent, _ := ctx.Value(spanKey{}).(*testSpan)
+	s := &testSpan{tracer: t, name: name, parent: parent, attrs: map[string]interface{}{}}
+	t.mu.Lock()
+	t.spans = append(t.spans, s)
+	t.mu.Unlock()
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+func (t *testTracer) Inject(ctx context.Context, header http.Header) {
+	if s, ok := ctx.Value(spanKey{}).(*testSpan); ok {
+		header.Set("X-Test-Span", s.path())
+	}
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.attrs[key] = value
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.errs = append(s.errs, err)
+}
+
+func (s *testSpan) End() {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	s.ended = true
+}
+
+// path returns the names of the span and its ancestors, e.g. "A/B/C".
+func (s *testSpan) path() string {
+	if s.parent == nil {
+		return s.name
+	}
+	return s.parent.path() + "/" + s.name
+}
+
+func (t *testTracer) find(path string) []*testSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var spans []*testSpan
+	for _, s := range t.spans {
+		if s.path() == path {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func TestTracer(t *testing.T) {
+	s := samplifytest.NewServer()
+	defer s.Close()
+	if _, err := s.NewClient().CreateProject(getProjectCriteria()); err != nil {
+		t.Fatal(err)
+	}
+	var mu sync.Mutex
+	var propagated []string
+	s.AddHook(func(w http.ResponseWriter, r *http.Request) bool {
+		mu.Lock()
+		propagated = append(propagated, r.Header.Get("X-Test-Span"))
+		mu.Unlock()
+		return false
+	})
+	s.InjectError(http.MethodGet, "/projects/*/lineItems/*", http.StatusServiceUnavailable, 1)
+
+	tracer := &testTracer{}
+	client := s.NewClient()
+	client.Options.Tracer = tracer
+	client.Options.Retry = &samplify.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+
+	ctx, root := tracer.Start(context.Background(), "order")
+	if _, err := client.GetLineItemByWithContext(ctx, "project001", "lineItem001"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.GetProjectByWithContext(ctx, "nope"); err == nil {
+		t.Fatal("expected an error for an unknown project")
+	}
+	// Errors returned before any request is sent.
+	if _, err := client.GetProjectByWithContext(ctx, ""); err == nil {
+		t.Fatal("expected a validation error")
+	}
+	query := samplify.NewQuery().Filter(samplify.QueryFieldBillingDate, samplify.Eq("2020/01/01"))
+	if _, err := client.GetAllProjectsWithContext(ctx, query); err == nil {
+		t.Fatal("expected a query field error")
+	}
+	root.End()
+
+	ops := tracer.find("order/GetLineItemBy")
+	if len(ops) != 1 {
+		t.Fatalf("expected one GetLineItemBy span, got %d", len(ops))
+	}
+	op := ops[0]
+	if !op.ended || len(op.errs) != 0 {
+		t.Errorf("unexpected span %+v", op)
+	}
+	for k, v := range map[string]interface{}{
+		samplify.AttrExtProjectID:  "project001",
+		samplify.AttrExtLineItemID: "lineItem001",
+		samplify.AttrStatusCode:    http.StatusOK,
+	} {
+		if op.attrs[k] != v {
+			t.Errorf("expected %s=%v on the operation span, got %v", k, v, op.attrs[k])
+		}
+	}
+
+	if n := len(tracer.find("order/GetLineItemBy/Login")); n != 1 {
+		t.Errorf("expected a Login child span, got %d", n)
+	}
+	if n := len(tracer.find("order/GetLineItemBy/Login/HTTP POST")); n != 1 {
+		t.Errorf("expected the login request in the Login span, got %d", n)
+	}
+	attempts := append(tracer.find("order/GetLineItemBy/HTTP GET"), tracer.find("order/GetLineItemBy/Retry/HTTP GET")...)
+	if len(attempts) != 2 {
+		t.Fatalf("expected an attempt and a retried one, got %d", len(attempts))
+	}
+	if attempts[0].attrs[samplify.AttrStatusCode] != http.StatusServiceUnavailable || len(attempts[0].errs) != 1 ||
+		attempts[1].attrs[samplify.AttrAttempt] != 2 {
+		t.Errorf("unexpected attempt spans %+v, %+v", attempts[0], attempts[1])
+	}
+	retries := tracer.find("order/GetLineItemBy/Retry")
+	if len(retries) != 1 || !retries[0].ended || len(retries[0].errs) != 0 || retries[0].attrs[samplify.AttrAttempt] != 2 {
+		t.Errorf("expected a Retry span around the second attempt, got %+v", retries)
+	}
+
+	failed := tracer.find("order/GetProjectBy")
+	if len(failed) != 2 || len(failed[0].errs) != 1 || failed[0].attrs[samplify.AttrExtProjectID] != "nope" {
+		t.Fatalf("expected the GetProjectBy span to record the error, got %+v", failed)
+	}
+	if len(failed[1].errs) != 1 || !failed[1].ended {
+		t.Errorf("expected the GetProjectBy span to record the validation error, got %+v", failed[1])
+	}
+	invalid := tracer.find("order/GetAllProjects")
+	if len(invalid) != 1 || len(invalid[0].errs) != 1 || !errors.Is(invalid[0].errs[0], samplify.ErrUnsupportedQueryField) {
+		t.Errorf("expected the GetAllProjects span to record the query field error, got %+v", invalid)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{
+		"order/GetLineItemBy/Login/HTTP POST",
+		"order/GetLineItemBy/HTTP GET",
+		"order/GetLineItemBy/Retry/HTTP GET",
+		"order/GetProjectBy/HTTP GET",
+	}
+	if fmt.Sprint(propagated) != fmt.Sprint(want) {
+		t.Errorf("expected propagated spans %v, got %v", strings.Join(want, ", "), strings.Join(propagated, ", "))
+	}
+}
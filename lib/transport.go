@@ -0,0 +1,78 @@
+package samplify
+
+import (
+	"net/http"
+	"time"
+)
+
+// Middleware wraps the http.RoundTripper that sends a Client's requests, for
+// example to log, trace or add headers to every request.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is an adapter to allow the use of ordinary functions as
+// http.RoundTripper.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// HeaderMiddleware returns a Middleware that sets the given headers on every
+// request.
+func HeaderMiddleware(header http.Header) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			// A RoundTripper must not modify the request it is given.
+			req = req.Clone(req.Context())
+			for k, v := range header {
+				req.Header[k] = v
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// newHTTPClient returns the http.Client that sends requests for options: the
+// caller-supplied HTTPClient or Transport, subject to the client-side limits
+// and wrapped in the Middleware chain.
+func (o *ClientOptions) newHTTPClient() *http.Client {
+	client := &http.Client{}
+	if o.HTTPClient != nil {
+		*client = *o.HTTPClient
+	}
+	if o.Transport != nil {
+		client.Transport = o.Transport
+	}
+	if client.Transport == nil {
+		client.Transport = http.DefaultTransport
+	}
+	client.Transport = o.newLimitTransport(o.newTraceTransport(o.newLogTransport(o.newMetricsTransport(client.Transport))))
+	for i := len(o.Middleware) - 1; i >= 0; i-- {
+		client.Transport = o.Middleware[i](client.Transport)
+	}
+	if client.Timeout == 0 && o.Timeout != nil {
+		client.Timeout = time.Second * time.Duration(*o.Timeout)
+	}
+	return client
+}
+
+// newRetryClient returns an http.Client that retries requests sent with
+// client, or client itself if retries are disabled.
+func (o *ClientOptions) newRetryClient(client *http.Client) *http.Client {
+	policy := o.Retry
+	if policy == nil && o.extraOptions != nil && o.extraOptions.retryEnabled {
+		policy = &RetryPolicy{MaxAttempts: o.extraOptions.maxRetries + 1}
+	}
+	if policy == nil {
+		return client
+	}
+	p := *policy
+	return &http.Client{
+		Transport: &retryTransport{client: client, policy: &p, logger: o.logger(), metrics: o.Metrics, tracer: o.tracer()},
+		// Redirects are followed by client.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
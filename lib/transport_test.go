@@ -0,0 +1,67 @@
+package samplify_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	samplify "github.com/researchnow/go-samplifyapi-client/lib"
+)
+
+func TestMiddlewareChain(t *testing.T) {
+	var header string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Test")
+	}))
+	defer ts.Close()
+
+	var order []string
+	trace := func(name string) samplify.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return samplify.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{
+		APIBaseURL: ts.URL,
+		Middleware: []samplify.Middleware{
+			trace("outer"),
+			samplify.HeaderMiddleware(http.Header{"X-Test": {"yes"}}),
+			trace("inner"),
+		},
+	})
+	client.Auth = getAuth()
+	client.GetAllProjects(nil)
+
+	if strings.Join(order, ",") != "outer,inner" {
+		t.Errorf("expected middleware to run outer first, got %v", order)
+	}
+	if header != "yes" {
+		t.Errorf("expected header to be set, got %q", header)
+	}
+}
+
+func TestCustomTransport(t *testing.T) {
+	var urls []string
+	client := samplify.NewClient("", "", "", &samplify.ClientOptions{
+		APIBaseURL: "http://samplify.invalid",
+		HTTPClient: &http.Client{
+			Transport: samplify.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				urls = append(urls, req.URL.String())
+				rec := httptest.NewRecorder()
+				rec.WriteString(`{"data":[]}`)
+				return rec.Result(), nil
+			}),
+		},
+	})
+	client.Auth = getAuth()
+	if _, err := client.GetAllProjects(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(urls) != 1 || urls[0] != "http://samplify.invalid/projects" {
+		t.Errorf("expected the request to go through the custom transport, got %v", urls)
+	}
+}
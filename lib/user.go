@@ -6,6 +6,15 @@ type UserResponse struct {
 	ResponseStatus ResponseStatus `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *UserResponse) Envelope() *Envelope[*User] {
+	return &Envelope[*User]{Data: r.User, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *UserResponse) setEnvelope(e *Envelope[*User]) {
+	r.User, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // User to hold any information related to the user.
 type User struct {
 	Applications []Application `json:"applications"`
@@ -30,6 +39,15 @@ type UserDetailsResponse struct {
 	ResponseStatus ResponseStatus `json:"status"`
 }
 
+// Envelope returns r as an Envelope.
+func (r *UserDetailsResponse) Envelope() *Envelope[*UserDetails] {
+	return &Envelope[*UserDetails]{Data: r.User, ResponseStatus: r.ResponseStatus}
+}
+
+func (r *UserDetailsResponse) setEnvelope(e *Envelope[*UserDetails]) {
+	r.User, r.ResponseStatus = e.Data, e.ResponseStatus
+}
+
 // CompanyUsersResponse holds api response object and returns a list of company users.
 type CompanyUsersResponse struct {
 	List           []*CompanyUser `json:"data"`
@@ -37,6 +55,15 @@ type CompanyUsersResponse struct {
 	Meta           Meta           `json:"meta"`
 }
 
+// Envelope returns r as a ListEnvelope.
+func (r *CompanyUsersResponse) Envelope() *ListEnvelope[*CompanyUser] {
+	return &ListEnvelope[*CompanyUser]{Data: r.List, ResponseStatus: r.ResponseStatus, Meta: r.Meta}
+}
+
+func (r *CompanyUsersResponse) setEnvelope(e *ListEnvelope[*CompanyUser]) {
+	r.List, r.ResponseStatus, r.Meta = e.Data, e.ResponseStatus, e.Meta
+}
+
 // User to hold any information related to the user.
 type UserDetails struct {
 	ID        int32     `json:"id,omitempty"`